@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/ghodss/yaml"
+	"github.com/moshloop/smarti/pkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ConfigShow = cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration from smarti.yml, ansible.cfg and flags",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			config := pkg.EffectiveConfig(cmd)
+			if config.Path != "" {
+				fmt.Printf("# %s\n", config.Path)
+			}
+			data, err := yaml.Marshal(config)
+			if err != nil {
+				fmt.Println("error:", err)
+			} else {
+				fmt.Printf("%s", data)
+			}
+		},
+	}
+
+	Config = cobra.Command{
+		Use:  "config",
+		Args: cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			print("Must specify a sub-command, run `smarti config --help` for more details")
+		},
+	}
+)
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"github.com/spf13/cobra"
 	"github.com/moshloop/smarti/pkg"
 	"fmt"
@@ -19,7 +20,7 @@ var (
 		Run: func(cmd *cobra.Command, args []string) {
 			log.Infof("Exporting images versions from %s/%s", cmd.Flag("inventory").Value.String(), cmd.Flag("limit").Value.String())
 
-			var inv = pkg.Parse(cmd)
+			var inv = pkg.LoadInventory(cmd)
 
 			for _, container := range inv.Containers() {
 				fmt.Printf("%s\n", strings.Replace(container.Image, ":", ": ", 1))
@@ -36,11 +37,14 @@ var (
 
 			log.Infof("Running containers on %s/%s", cmd.Flag("inventory").Value.String(), cmd.Flag("limit").Value.String())
 
-			var inv = pkg.Parse(cmd)
+			var inv = pkg.LoadInventory(cmd)
+			output := pkg.EffectiveConfig(cmd).Output
 
+			specs := inv.ToSpecs()
 			for _, container := range inv.Containers() {
-				fmt.Printf("%s\n", container.ToDeployment())
+				specs = append(specs, container.ToSpecs()...)
 			}
+			fmt.Printf("%s\n", pkg.MarshalSpecs(specs, output))
 
 		},
 	}
@@ -55,7 +59,7 @@ var (
 
 			k8s, config := pkg.GetK8sClient("")
 
-			nodes, _ := k8s.CoreV1().Nodes().List(meta_v1.ListOptions{})
+			nodes, _ := k8s.CoreV1().Nodes().List(context.TODO(), meta_v1.ListOptions{})
 			endpoint := ""
 			for _, node := range nodes.Items {
 				if endpoint != "" ||  node.Spec.Unschedulable {
@@ -68,8 +72,11 @@ var (
 					}
 				}
 			}
-			namespace := config.Contexts[config.CurrentContext].Namespace
-			svcs, err := k8s.CoreV1().Services(namespace).List(meta_v1.ListOptions{})
+			namespace := pkg.EffectiveConfig(cmd).Namespace
+			if namespace == "" {
+				namespace = config.Contexts[config.CurrentContext].Namespace
+			}
+			svcs, err := k8s.CoreV1().Services(namespace).List(context.TODO(), meta_v1.ListOptions{})
 			if err != nil {
 				panic(err)
 			}
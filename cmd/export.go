@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/moshloop/smarti/pkg"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var (
+	Export = cobra.Command{
+		Use:   "export",
+		Short: "Export the fully resolved inventory as a snapshot for reproducible renders",
+		Args:  cobra.MinimumNArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+
+			var inv = pkg.Parse(cmd)
+			includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+			if secrets := inv.SnapshotSecrets(); len(secrets) > 0 && !includeSecrets {
+				log.Fatalf("Snapshots store secrets in plain text, pass --include-secrets to export %s", strings.Join(secrets, ", "))
+			}
+
+			data, err := json.MarshalIndent(pkg.NewSnapshot(inv), "", "  ")
+
+			if err != nil {
+				fmt.Println("error:", err)
+			} else {
+				fmt.Printf("%s\n", data)
+			}
+		},
+	}
+)
@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/spf13/cobra"
 	"github.com/moshloop/smarti/cmd"
+	"github.com/moshloop/smarti/pkg"
 	"os"
 	log "github.com/sirupsen/logrus"
 )
@@ -25,18 +26,32 @@ func main() {
 
 	}
 
-	root.PersistentFlags().StringP("inventory", "i", "", "Specify inventory host path or comma separated host list")
+	// smarti.yml and ansible.cfg provide the defaults for flags that are not specified
+	config := pkg.LoadConfig()
+
+	root.PersistentFlags().StringP("inventory", "i", config.Inventory, "Specify inventory host path or comma separated host list")
 	root.PersistentFlags().Bool("version", false, "")
 	root.PersistentFlags().StringSliceP("extra-vars", "e", []string{}, "Set additional variables as key=value or YAML/JSON, if filename prepend with @")
 	root.PersistentFlags().StringP("limit", "l", "", "Limit selected hosts to an additional pattern")
+	root.PersistentFlags().String("vault-password-file", config.VaultPasswordFile, "Vault password file used to decrypt ansible-vault files and !vault values")
 	root.PersistentFlags().CountP("loglevel", "v", "Increase logging level")
 
 	cmd.Containers.AddCommand(&cmd.Versions)
 	cmd.Containers.AddCommand(&cmd.Spec)
 	cmd.Containers.AddCommand(&cmd.Health)
 	cmd.Health.Flags().Bool("print", false, "Print IP:PORT details for running services (useful to pipe into xargs for additional checks)")
-	cmd.Containers.PersistentFlags().String("image-versions", "", "A path to yml or json file containing image versions")
-	root.AddCommand(&cmd.List, &cmd.Containers)
+	// config show accepts the same flags so that it prints the configuration the containers commands use
+	for _, c := range []*cobra.Command{&cmd.Containers, &cmd.Config} {
+		c.PersistentFlags().String("image-versions", config.ImageVersions, "A path to yml or json file containing image versions")
+		c.PersistentFlags().StringP("output", "o", config.Output, "Output format, one of yaml|json")
+		c.PersistentFlags().String("kube-version", config.KubeVersion, "Target kubernetes version used to select API versions, e.g. 1.22, defaults to the latest")
+		c.PersistentFlags().StringP("namespace", "n", config.Namespace, "Namespace for all generated objects, overrides the namespace of groups and containers")
+	}
+	cmd.Containers.PersistentFlags().String("from-snapshot", "", "Render from a snapshot created by smarti export instead of parsing the inventory")
+	cmd.Export.Flags().String("image-versions", config.ImageVersions, "A path to yml or json file containing image versions")
+	cmd.Export.Flags().Bool("include-secrets", false, "Export secret values, decrypted vault values and registry credentials, the snapshot must then be protected like the vault password")
+	cmd.Config.AddCommand(&cmd.ConfigShow)
+	root.AddCommand(&cmd.List, &cmd.Containers, &cmd.Config, &cmd.Export)
 
 	if err := root.Execute(); err != nil {
 		os.Exit(1)
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AnsibleConfig holds the ansible.cfg settings that are relevant to smarti, roles_path and the other
+// settings are ignored as smarti does not run roles or plays
+type AnsibleConfig struct {
+	Path              string
+	Inventory         string
+	VaultPasswordFile string
+	HashBehaviour     string
+}
+
+// FindAnsibleConfig returns the first ansible.cfg found using the same search order as ansible:
+// ANSIBLE_CONFIG, ./ansible.cfg, ~/.ansible.cfg and /etc/ansible/ansible.cfg
+func FindAnsibleConfig() string {
+	candidates := []string{os.Getenv("ANSIBLE_CONFIG"), "ansible.cfg"}
+	if home := homeDir(); home != "" {
+		candidates = append(candidates, filepath.Join(home, ".ansible.cfg"))
+	}
+	candidates = append(candidates, "/etc/ansible/ansible.cfg")
+
+	for _, file := range candidates {
+		if file == "" {
+			continue
+		}
+		if stat, err := os.Stat(file); err == nil && !stat.IsDir() {
+			return file
+		}
+	}
+	return ""
+}
+
+// LoadAnsibleConfig parses the [defaults] of the active ansible.cfg, ANSIBLE_* environment variables
+// take precedence over the file just like they do in ansible
+func LoadAnsibleConfig() AnsibleConfig {
+	config := AnsibleConfig{Path: FindAnsibleConfig()}
+
+	if config.Path != "" {
+		log.Debugf("Using ansible config from %s", config.Path)
+		cfg, err := LoadIni(SafeRead(config.Path))
+		if err != nil {
+			log.Warnf("Error parsing %s: %v", config.Path, err)
+		} else {
+			defaults := IniMapFlat(cfg)
+			dir := path.Dir(config.Path)
+			config.Inventory = configPath(dir, defaults["defaults.inventory"])
+			config.VaultPasswordFile = configPath(dir, defaults["defaults.vault_password_file"])
+			config.HashBehaviour = defaults["defaults.hash_behaviour"]
+		}
+	}
+
+	if inventory := os.Getenv("ANSIBLE_INVENTORY"); inventory != "" {
+		config.Inventory = inventory
+	}
+	if file := os.Getenv("ANSIBLE_VAULT_PASSWORD_FILE"); file != "" {
+		config.VaultPasswordFile = file
+	}
+	if behaviour := os.Getenv("ANSIBLE_HASH_BEHAVIOUR"); behaviour != "" {
+		config.HashBehaviour = behaviour
+	}
+	return config
+}
+
+// configPath expands ~ and resolves paths relative to the directory containing ansible.cfg
+func configPath(dir string, value string) string {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Contains(value, ",") {
+		// comma separated host lists are passed through as is
+		return value
+	}
+	if strings.HasPrefix(value, "~/") {
+		return filepath.Join(homeDir(), value[2:])
+	}
+	if !filepath.IsAbs(value) {
+		return filepath.Join(dir, value)
+	}
+	return value
+}
@@ -0,0 +1,158 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultAutoscaleCpu is the target cpu utilization (in percent of requests) when no metrics are specified
+const DefaultAutoscaleCpu = 80
+
+// Autoscale configures a HorizontalPodAutoscaler, cpu and memory are target utilizations in percent of the requests
+type Autoscale struct {
+	Min     int32                      `json:"min,omitempty"`
+	Max     int32                      `json:"max,omitempty"`
+	Cpu     int32                      `json:"cpu,omitempty"`
+	Memory  int32                      `json:"memory,omitempty"`
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+}
+
+// IsAutoscaled returns true if a HorizontalPodAutoscaler manages the replicas of the container
+func (c Container) IsAutoscaled() bool {
+	return c.Autoscale != nil && (c.Kind == DeploymentKind || c.Kind == StatefulSetKind)
+}
+
+// ToReplicas is omitted when autoscaling so that applying the spec does not reset the replicas chosen by the autoscaler
+func (c Container) ToReplicas() *int32 {
+	if c.IsAutoscaled() {
+		return nil
+	}
+	return &c.Replicas
+}
+
+// ToHorizontalPodAutoscaler returns an autoscaling/v2 HorizontalPodAutoscaler, or the legacy autoscaling/v2beta2
+// equivalent for clusters older than 1.23
+func (c Container) ToHorizontalPodAutoscaler() interface{} {
+	version := c.KubeVersion()
+	if !version.AtLeast(12) {
+		log.Fatalf("[%s] Autoscaling requires kube_version 1.12 or later, got %s", c.Service, version)
+	}
+	target := autoscalingv2.CrossVersionObjectReference{
+		APIVersion: c.KubeVersion().AppsAPIVersion(),
+		Kind:       "Deployment",
+		Name:       c.Service,
+	}
+	if c.Kind == StatefulSetKind {
+		target.APIVersion = c.KubeVersion().StatefulSetAPIVersion()
+		target.Kind = "StatefulSet"
+	}
+	hpa := autoscalingv2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "autoscaling/v2",
+			Kind:       "HorizontalPodAutoscaler",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: target,
+			MinReplicas:    &c.Autoscale.Min,
+			MaxReplicas:    c.Autoscale.Max,
+			Metrics:        c.Autoscale.ToMetrics(),
+		},
+	}
+	if version.AtLeast(23) {
+		return hpa
+	}
+	legacy, err := ToLegacyHorizontalPodAutoscaler(hpa)
+	if err != nil {
+		log.Fatalf("[%s] Cannot convert the autoscaler to %s: %v", c.Service, version.AutoscalingAPIVersion(), err)
+	}
+	legacy.APIVersion = version.AutoscalingAPIVersion()
+	return legacy
+}
+
+// ToLegacyHorizontalPodAutoscaler converts a HorizontalPodAutoscaler to the autoscaling/v2beta2 schema, which
+// autoscaling/v2 promoted without changes, so the fields are copied by their json names
+func ToLegacyHorizontalPodAutoscaler(hpa autoscalingv2.HorizontalPodAutoscaler) (autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+	var legacy autoscalingv2beta2.HorizontalPodAutoscaler
+	err := deepCopy(&legacy, hpa)
+	legacy.APIVersion = "autoscaling/v2beta2"
+	return legacy, err
+}
+
+func (a Autoscale) ToMetrics() []autoscalingv2.MetricSpec {
+	var metrics []autoscalingv2.MetricSpec
+	if a.Cpu > 0 {
+		metrics = append(metrics, ToResourceMetric(v1.ResourceCPU, a.Cpu))
+	}
+	if a.Memory > 0 {
+		metrics = append(metrics, ToResourceMetric(v1.ResourceMemory, a.Memory))
+	}
+	return append(metrics, a.Metrics...)
+}
+
+func ToResourceMetric(name v1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
+
+// applyAutoscaleDefaults fills in unspecified autoscale settings from the group defaults and validates them
+func (c *Container) applyAutoscaleDefaults(defaults *Autoscale) {
+	if c.Autoscale == nil {
+		// group defaults only apply to the kinds that can be autoscaled
+		if defaults == nil || (c.Kind != DeploymentKind && c.Kind != StatefulSetKind) {
+			return
+		}
+		autoscale := *defaults
+		c.Autoscale = &autoscale
+	} else if defaults != nil {
+		if c.Autoscale.Min == 0 {
+			c.Autoscale.Min = defaults.Min
+		}
+		if c.Autoscale.Max == 0 {
+			c.Autoscale.Max = defaults.Max
+		}
+		if c.Autoscale.Cpu == 0 && c.Autoscale.Memory == 0 && len(c.Autoscale.Metrics) == 0 {
+			c.Autoscale.Cpu = defaults.Cpu
+			c.Autoscale.Memory = defaults.Memory
+			c.Autoscale.Metrics = defaults.Metrics
+		}
+	}
+
+	if !c.IsAutoscaled() {
+		log.Warnf("[%s] Autoscaling is not supported for %s", c.Service, c.Kind)
+		c.Autoscale = nil
+		return
+	}
+	if c.Autoscale.Min == 0 {
+		c.Autoscale.Min = c.Replicas
+	}
+	if c.Autoscale.Min == 0 {
+		c.Autoscale.Min = 1
+	}
+	if c.Autoscale.Max < c.Autoscale.Min {
+		log.Errorf("[%s] Autoscale max %d is less than min %d", c.Service, c.Autoscale.Max, c.Autoscale.Min)
+		c.Autoscale.Max = c.Autoscale.Min
+	}
+	if c.Autoscale.Cpu == 0 && c.Autoscale.Memory == 0 && len(c.Autoscale.Metrics) == 0 {
+		c.Autoscale.Cpu = DefaultAutoscaleCpu
+	}
+	// utilization is relative to the requests
+	requests := c.ToResources().Requests
+	if _, ok := requests[v1.ResourceCPU]; c.Autoscale.Cpu > 0 && !ok {
+		log.Warnf("[%s] Autoscaling on cpu without a cpu request", c.Service)
+	}
+	if _, ok := requests[v1.ResourceMemory]; c.Autoscale.Memory > 0 && !ok {
+		log.Warnf("[%s] Autoscaling on memory without a memory request", c.Service)
+	}
+}
@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const ConfigFile = "smarti.yml"
+
+// Config holds project wide defaults read from smarti.yml, flags take precedence over these values
+type Config struct {
+	Path              string            `json:"-"`
+	Inventory         string            `json:"inventory,omitempty"`
+	VaultPasswordFile string            `json:"vault_password_file,omitempty"`
+	ImageVersions     string            `json:"image_versions,omitempty"`
+	Output            string            `json:"output,omitempty"`
+	KubeVersion       string            `json:"kube_version,omitempty"`
+	Namespace         string            `json:"namespace,omitempty"`
+	HashBehaviour     string            `json:"hash_behaviour,omitempty"`
+	Limit             string            `json:"limit,omitempty"`
+	ExtraVars         []string          `json:"extra_vars,omitempty"`
+	DockerRegistry    string            `json:"docker_registry,omitempty"`
+	LatestToTagHarbor string            `json:"latest_to_tag_harbor,omitempty"`
+	CommonLabels      map[string]string `json:"common_labels,omitempty"`
+	CommonAnnotations map[string]string `json:"common_annotations,omitempty"`
+}
+
+// FindConfig returns the path of SMARTI_CONFIG or the nearest smarti.yml in the current or a parent directory
+func FindConfig() string {
+	if file := os.Getenv("SMARTI_CONFIG"); file != "" {
+		return file
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		file := filepath.Join(dir, ConfigFile)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+var (
+	loadedConfig Config
+	loadConfig   sync.Once
+)
+
+// LoadConfig parses smarti.yml, falling back to ansible.cfg for the inventory, vault password file
+// and hash_behaviour, both files are only read once
+func LoadConfig() Config {
+	loadConfig.Do(func() {
+		loadedConfig = readConfig()
+	})
+	return loadedConfig
+}
+
+func readConfig() Config {
+	config := Config{Path: FindConfig()}
+	if config.Path != "" {
+		data, err := ioutil.ReadFile(config.Path)
+		if err != nil {
+			log.Warnf("Error reading %s: %v", config.Path, err)
+		} else if err := yaml.Unmarshal(data, &config); err != nil {
+			log.Warnf("Error parsing %s: %v", config.Path, err)
+		}
+		dir := filepath.Dir(config.Path)
+		config.Inventory = configPath(dir, config.Inventory)
+		config.ImageVersions = configPath(dir, config.ImageVersions)
+		config.VaultPasswordFile = configPath(dir, config.VaultPasswordFile)
+	}
+
+	ansible := LoadAnsibleConfig()
+	if config.Inventory == "" {
+		config.Inventory = ansible.Inventory
+	}
+	if config.VaultPasswordFile == "" {
+		config.VaultPasswordFile = ansible.VaultPasswordFile
+	}
+	if config.HashBehaviour == "" {
+		config.HashBehaviour = ansible.HashBehaviour
+	}
+	if config.Output == "" {
+		config.Output = "yaml"
+	}
+	return config
+}
+
+// EffectiveConfig returns the config with any flags explicitly specified on cmd applied
+func EffectiveConfig(cmd *cobra.Command) Config {
+	config := LoadConfig()
+	flags := map[string]*string{
+		"inventory":           &config.Inventory,
+		"image-versions":      &config.ImageVersions,
+		"output":              &config.Output,
+		"kube-version":        &config.KubeVersion,
+		"vault-password-file": &config.VaultPasswordFile,
+		"namespace":           &config.Namespace,
+		"limit":               &config.Limit,
+	}
+	for name, value := range flags {
+		if flag := cmd.Flag(name); flag != nil && flag.Changed {
+			*value = flag.Value.String()
+		}
+	}
+	if flag := cmd.Flag("extra-vars"); flag != nil && flag.Changed {
+		config.ExtraVars, _ = cmd.Flags().GetStringSlice("extra-vars")
+	}
+	return config
+}
+
+// Vars returns the inventory variables that the config provides defaults for
+func (config Config) Vars() map[string]interface{} {
+	vars := make(map[string]interface{})
+	if config.KubeVersion != "" {
+		vars["kube_version"] = config.KubeVersion
+	}
+	if config.Namespace != "" {
+		vars["namespace"] = config.Namespace
+	}
+	if config.DockerRegistry != "" {
+		vars["docker_registry"] = config.DockerRegistry
+	}
+	if config.LatestToTagHarbor != "" {
+		vars["latest_to_tag_harbor"] = config.LatestToTagHarbor
+	}
+	if len(config.CommonLabels) > 0 {
+		vars["common_labels"] = ToGenericMap(config.CommonLabels)
+	}
+	if len(config.CommonAnnotations) > 0 {
+		vars["common_annotations"] = ToGenericMap(config.CommonAnnotations)
+	}
+	return vars
+}
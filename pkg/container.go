@@ -1,20 +1,19 @@
 package pkg
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/docker/cli/cli/compose/loader"
 	"github.com/docker/cli/cli/compose/types"
-	"k8s.io/api/extensions/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"github.com/ghodss/yaml"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	log "github.com/sirupsen/logrus"
-	"github.com/flosch/pongo2"
 	"strconv"
 	"strings"
-	"errors"
 	"path"
 	"io/ioutil"
 )
@@ -22,39 +21,78 @@ import (
 type StringOrInt interface{}
 
 type Container struct {
-	Image          string            `json:"image,omitempty"`
-	ImageName      string
-	ImageTag       string
-	ImageDigest    string
-	Ingress        string            `json:"ingress,omitempty"`
-	Args           []string          `json:"args,omitempty"`
-	Command        []string          `json:"command,omitempty"`
-	Entrypoint     []string          `json:"entrypoint,omitempty"`
-	WorkingDir     string            `json:"working_dir,omitempty"`
-	Hostname       string            `json:"hostname,omitempty"`
-	User           string            `json:"user,omitempty"`
-	Privileged     bool              `json:"privileged,omitempty"`
-	Service        string            `json:"service,omitempty"`
-	ServiceType    string            `json:"service_type,omitempty"`
-	Mem            int               `json:"mem,omitempty"`
-	Cpu            StringOrInt       `json:"cpu,omitempty"`
-	Replicas       int32             `json:"replicas,omitempty"`
-	Commands       []string          `json:"commands,omitempty"`
-	Env            map[string]string `json:"env,omitempty"`
-	Files          map[string]string `json:"files,omitempty"`
-	Templates      map[string]string `json:"templates,omitempty"`
-	Mounts         map[string]string `json:"mounts,omitempty"`
-	Labels         map[string]string `json:"labels,omitempty"`
-	Annotations    map[string]string `json:"annotations,omitempty"`
-	ContainerName  string            `json:"container_name,omitempty"`
-	Ports          []ContainerPort   `json:"ports,omitempty"`
-	Source         interface{}       `json:"source,omitempty"`
-	ReadinessProbe *HealthCheck      `json:"readinessProbe,omitempty"`
-	LivenessProbe  *HealthCheck      `json:"livenessProbe,omitempty"`
-	Group          Group
-	Specs          []interface{}
-	K8VolumeMounts []v1.VolumeMount
-	K8Volumes      []v1.Volume
+	Image               string `json:"image,omitempty"`
+	ImageName           string
+	ImageTag            string
+	ImageDigest         string
+	Ingress             string                       `json:"ingress,omitempty"`
+	Ingresses           []IngressRoute               `json:"ingresses,omitempty"`
+	IngressClass        string                       `json:"ingress_class,omitempty"`
+	IngressAnnotations  map[string]string            `json:"ingress_annotations,omitempty"`
+	TLSSecret           string                       `json:"tls_secret,omitempty"`
+	CertIssuer          string                       `json:"cert_issuer,omitempty"`
+	IngressMode         string                       `json:"ingress_mode,omitempty"`
+	Gateway             string                       `json:"gateway,omitempty"`
+	Args                []string                     `json:"args,omitempty"`
+	Command             []string                     `json:"command,omitempty"`
+	Entrypoint          []string                     `json:"entrypoint,omitempty"`
+	WorkingDir          string                       `json:"working_dir,omitempty"`
+	Hostname            string                       `json:"hostname,omitempty"`
+	User                string                       `json:"user,omitempty"`
+	Privileged          bool                         `json:"privileged,omitempty"`
+	Security
+	Scheduling
+	Service             string                       `json:"service,omitempty"`
+	ServiceType         string                       `json:"service_type,omitempty"`
+	Mem                 int                          `json:"mem,omitempty"`
+	Cpu                 StringOrInt                  `json:"cpu,omitempty"`
+	Replicas            int32                        `json:"replicas,omitempty"`
+	Commands            []string                     `json:"commands,omitempty"`
+	Env                 map[string]string            `json:"env,omitempty"`
+	Files               map[string]string            `json:"files,omitempty"`
+	Templates           map[string]string            `json:"templates,omitempty"`
+	Mounts              map[string]string            `json:"mounts,omitempty"`
+	Labels              map[string]string            `json:"labels,omitempty"`
+	Annotations         map[string]string            `json:"annotations,omitempty"`
+	ContainerName       string                       `json:"container_name,omitempty"`
+	Ports               []ContainerPort              `json:"ports,omitempty"`
+	Source              interface{}                  `json:"source,omitempty"`
+	ReadinessProbe      *HealthCheck                 `json:"readinessProbe,omitempty"`
+	LivenessProbe       *HealthCheck                 `json:"livenessProbe,omitempty"`
+	StartupProbe        *HealthCheck                 `json:"startupProbe,omitempty"`
+	ConfigData          map[string]map[string]string `json:"config_data,omitempty"`
+	Kind                string                       `json:"kind,omitempty"`
+	Component           string                       `json:"component,omitempty"`
+	Namespace           string                       `json:"namespace,omitempty"`
+	ServiceAccount      string                       `json:"service_account,omitempty"`
+	CreateAccount       *bool                        `json:"create_service_account,omitempty"`
+	AutomountToken      *bool                        `json:"automount_token,omitempty"`
+	Rbac                []RbacRule                   `json:"rbac,omitempty"`
+	ImagePullSecrets    []string                     `json:"image_pull_secrets,omitempty"`
+	ImagePullPolicy     string                       `json:"image_pull_policy,omitempty"`
+	Volumes             []Volume                     `json:"volumes,omitempty"`
+	PodManagementPolicy string                       `json:"pod_management_policy,omitempty"`
+	UpdateStrategy      string                       `json:"update_strategy,omitempty"`
+	Schedule            string                       `json:"schedule,omitempty"`
+	BackoffLimit        *int32                       `json:"backoff_limit,omitempty"`
+	Completions         *int32                       `json:"completions,omitempty"`
+	ConcurrencyPolicy   string                       `json:"concurrency_policy,omitempty"`
+	RestartPolicy       string                       `json:"restart_policy,omitempty"`
+	Secrets             []Secret                     `json:"secrets,omitempty"`
+	Sidecars            []*Container                 `json:"sidecars,omitempty"`
+	InitContainers      []*Container                 `json:"init_containers,omitempty"`
+	initContainer       bool
+	Autoscale           *Autoscale                   `json:"autoscale,omitempty"`
+	MinAvailable        *intstr.IntOrString          `json:"min_available,omitempty"`
+	MaxUnavailable      *intstr.IntOrString          `json:"max_unavailable,omitempty"`
+	DependsOn           []string                     `json:"depends_on,omitempty"`
+	AllowFrom           []string                     `json:"allow_from,omitempty"`
+	Requests            Resources                    `json:"requests,omitempty"`
+	Limits              Resources                    `json:"limits,omitempty"`
+	Group               Group                        `json:"-"`
+	Specs               []interface{}
+	K8VolumeMounts      []v1.VolumeMount
+	K8Volumes           []v1.Volume
 }
 
 type ContainerPort struct {
@@ -64,36 +102,55 @@ type ContainerPort struct {
 	Protocol  string
 }
 
-type HealthCheck struct {
-	Cmd     string
-	Url     string
-	Port    int
-	Period  int32 `json:"period,omitempty"`
-	Timeout int32 `json:"timeout,omitempty"`
-	Delay   int32 `json:"delay,omitempty"`
-}
-
 type ContainerDefaults struct {
-	ReadinessProbe *HealthCheck      `json:"readinessProbe,omitempty"`
-	LivenessProbe  *HealthCheck      `json:"livenessProbe,omitempty"`
-	ServiceType    string            `json:"service_type,omitempty"`
-	Replicas       int32             `json:"replicas,omitempty"`
-	Mem            int               `json:"mem,omitempty"`
-	Cpu            StringOrInt       `json:"cpu,omitempty"`
-	Env            map[string]string `json:"env,omitempty"`
-	Labels         map[string]string `json:"labels,omitempty"`
-	Annotations    map[string]string `json:"annotations,omitempty"`
-	Ingress        string            `json:"ingress,omitempty"`
+	ReadinessProbe      *HealthCheck        `json:"readinessProbe,omitempty"`
+	LivenessProbe       *HealthCheck        `json:"livenessProbe,omitempty"`
+	StartupProbe        *HealthCheck        `json:"startupProbe,omitempty"`
+	ServiceType         string              `json:"service_type,omitempty"`
+	Replicas            int32               `json:"replicas,omitempty"`
+	Mem                 int                 `json:"mem,omitempty"`
+	Cpu                 StringOrInt         `json:"cpu,omitempty"`
+	Env                 map[string]string   `json:"env,omitempty"`
+	Labels              map[string]string   `json:"labels,omitempty"`
+	Annotations         map[string]string   `json:"annotations,omitempty"`
+	Ingress             string              `json:"ingress,omitempty"`
+	IngressDomains      []string            `json:"ingress_domains,omitempty"`
+	IngressClass        string              `json:"ingress_class,omitempty"`
+	IngressAnnotations  map[string]string   `json:"ingress_annotations,omitempty"`
+	TLSSecret           string              `json:"tls_secret,omitempty"`
+	CertIssuer          string              `json:"cert_issuer,omitempty"`
+	IngressMode         string              `json:"ingress_mode,omitempty"`
+	Gateway             string              `json:"gateway,omitempty"`
+	Kind                string              `json:"kind,omitempty"`
+	Component           string              `json:"component,omitempty"`
+	Namespace           string              `json:"namespace,omitempty"`
+	AutomountToken      *bool               `json:"automount_token,omitempty"`
+	ImagePullSecrets    []string            `json:"image_pull_secrets,omitempty"`
+	ImagePullPolicy     string              `json:"image_pull_policy,omitempty"`
+	PodManagementPolicy string              `json:"pod_management_policy,omitempty"`
+	UpdateStrategy      string              `json:"update_strategy,omitempty"`
+	Volume              Volume              `json:"volume,omitempty"`
+	Sidecars            []*Container        `json:"sidecars,omitempty"`
+	Autoscale           *Autoscale          `json:"autoscale,omitempty"`
+	MinAvailable        *intstr.IntOrString `json:"min_available,omitempty"`
+	MaxUnavailable      *intstr.IntOrString `json:"max_unavailable,omitempty"`
+	Requests            Resources           `json:"requests,omitempty"`
+	Limits              Resources           `json:"limits,omitempty"`
+	RequestRatios       map[string]float64  `json:"request_ratios,omitempty"`
+	User                string              `json:"user,omitempty"`
+	Security
+	Scheduling
 }
 
 func (port ContainerPort) String() string {
 	return fmt.Sprintf("%d:%d", port.Published, port.Target)
 }
 
+// ToName returns the last path segment of name, without dots and with underscores (common in compose) replaced by dashes
 func ToName(name string) string {
 	path := strings.Split(name, "/")
 	name = path[len(path)-1]
-	return strings.Replace(strings.ToLower(name), ".", "", -1)
+	return strings.Replace(strings.Replace(strings.ToLower(name), ".", "", -1), "_", "-", -1)
 }
 
 func (c *Container) PostProcess() {
@@ -129,17 +186,32 @@ func (c *Container) PostProcess() {
 		c.ServiceType = defaults.ServiceType
 	}
 
-	if c.Cpu == "0" || c.Cpu == 0 || c.Cpu == nil {
-		c.Cpu = ""
+	if c.Kind == "" {
+		c.Kind = defaults.Kind
+	}
+	c.Kind = strings.ToLower(c.Kind)
+	if c.Kind == "" {
+		c.Kind = DeploymentKind
+	}
+	c.applyVolumeDefaults(defaults.Volume)
+
+	if c.PodManagementPolicy == "" {
+		c.PodManagementPolicy = defaults.PodManagementPolicy
 	}
-	if c.Cpu == "" || c.Cpu == nil || c.Cpu == 0 {
+	if c.UpdateStrategy == "" {
+		c.UpdateStrategy = defaults.UpdateStrategy
+	}
+
+	if IsZeroQuantity(c.Cpu) {
 		c.Cpu = defaults.Cpu
 	}
+	c.applyResourceDefaults(defaults)
+	c.applySecurityDefaults(defaults)
+	c.applySchedulingDefaults(defaults)
+	c.applyRegistryDefaults(defaults)
 
-	c.ImageName = strings.Split(c.Image, ":")[0]
-	if strings.Contains(c.Image, ":") {
-		c.ImageTag = strings.Split(c.Image, ":")[1]
-	} else {
+	c.ImageName, c.ImageTag, c.ImageDigest = ParseImage(c.Image)
+	if c.ImageTag == "" && c.ImageDigest == "" {
 		c.ImageTag = "latest"
 	}
 
@@ -151,36 +223,26 @@ func (c *Container) PostProcess() {
 		c.Service = c.ImageName
 	}
 	c.Service = ToName(c.Service)
+	// containers are parsed before group variables are merged, so secret values are interpolated here
+	c.applySecretDefaults()
 
-	if c.Ingress == "" && defaults.Ingress != "" {
-		c.Ingress = c.Service + "." + defaults.Ingress
+	if c.Component == "" {
+		c.Component = defaults.Component
 	}
-	if len(c.Ports) > 0 && c.ReadinessProbe == nil {
-		c.ReadinessProbe = &HealthCheck{
-			Port: c.Ports[0].Target,
-		}
-
-		if defaults.ReadinessProbe != nil  {
-			c.ReadinessProbe.Delay = defaults.ReadinessProbe.Delay
-			c.ReadinessProbe.Timeout = defaults.ReadinessProbe.Timeout
-			c.ReadinessProbe.Period = defaults.ReadinessProbe.Period
-
-		}
-	}
-
-	if c.ReadinessProbe != nil && c.LivenessProbe == nil {
-		c.LivenessProbe = c.ReadinessProbe
+	if c.Namespace == "" {
+		c.Namespace = defaults.Namespace
 	}
-	if c.LivenessProbe != nil && defaults.LivenessProbe != nil {
-		if c.LivenessProbe.Timeout == 0 {
-			c.LivenessProbe.Timeout = defaults.LivenessProbe.Timeout
-		}
-		if c.LivenessProbe.Period == 0 {
-			c.LivenessProbe.Period = defaults.LivenessProbe.Period
-		}
-	} else if defaults.LivenessProbe != nil {
-		c.LivenessProbe = defaults.LivenessProbe
+	if c.AutomountToken == nil {
+		c.AutomountToken = defaults.AutomountToken
 	}
+	c.applyMetadataDefaults(defaults)
+
+	c.applyIngressDefaults(defaults)
+	c.applyProbeDefaults(defaults)
+
+	c.applyAutoscaleDefaults(defaults.Autoscale)
+	c.applyDisruptionDefaults(defaults)
+	c.processSidecars()
 
 	versions := c.Group.Vars["image_versions"]
 	versionsMap := make(map[string]interface{})
@@ -189,6 +251,7 @@ func (c *Container) PostProcess() {
 	}
 	if version, ok := versionsMap[c.ImageName]; ok {
 		c.ImageTag = fmt.Sprintf("%s", version)
+		c.ImageDigest = ""
 		c.Image = c.ImageName + ":" + c.ImageTag
 		log.Infof("[%s] Using %s specified in version file", c.ImageName, c.ImageTag)
 		return
@@ -207,6 +270,11 @@ func (c *Container) PostProcess() {
 }
 
 func (port *ContainerPort) UnmarshalJSON(b []byte) error {
+	if strings.HasPrefix(string(b), "{") {
+		// ports serialized in a snapshot
+		type containerPort ContainerPort
+		return json.Unmarshal(b, (*containerPort)(port))
+	}
 	str, _ := strconv.Unquote(string(b))
 	port.Published, _ = strconv.Atoi(strings.Split(str, ":")[0])
 	if !strings.Contains(str, ":") {
@@ -226,30 +294,8 @@ func (c Container) ToMem() resource.Quantity {
 	return qty
 }
 
-// Sub subtracts the provided quantity from the current value in place. If the current
-// value is zero, the format of the quantity will be updated to the format of y.
-func Ratio(q resource.Quantity, ratio int) resource.Quantity {
-	qty :=  *resource.NewScaledQuantity(q.MilliValue() / int64(ratio), resource.Milli)
-	qty.Format = q.Format
-	return qty
-}
-
 func (c Container) ToCpu() (resource.Quantity, error) {
-	var qty resource.Quantity
-	switch v := c.Cpu.(type) {
-	case int:
-		qty, _ = resource.ParseQuantity(strconv.Itoa(v))
-		break
-	case float64:
-		qty, _ = resource.ParseQuantity(strconv.Itoa(int(v)))
-		break
-	case string:
-		qty, _ = resource.ParseQuantity(v)
-		break
-	default:
-		return resource.Quantity{}, errors.New("Missing quantity")
-	}
-	return qty, nil
+	return ParseQuantity(c.Cpu)
 }
 
 func (c Container) ToPorts() []v1.ServicePort {
@@ -281,26 +327,6 @@ func (c Container) ToContainerPorts() []v1.ContainerPort {
 	return ports
 }
 
-func (c Container) ToResources() v1.ResourceRequirements {
-
-	limits := v1.ResourceList{}
-	requests := v1.ResourceList{}
-	if c.Mem > 0 {
-		limits[v1.ResourceMemory] = c.ToMem()
-		requests[v1.ResourceMemory] = Ratio(c.ToMem(), 2)
-	}
-	cpu, err := c.ToCpu()
-	if err == nil && cpu.Value() > 0 {
-		limits[v1.ResourceCPU] = cpu
-		requests[v1.ResourceCPU] = Ratio(cpu, 4);
-	}
-
-	return v1.ResourceRequirements{
-		Limits:   limits,
-		Requests: requests,
-	}
-}
-
 func (c Container) ToEnvVars() []v1.EnvVar {
 
 	var vars []v1.EnvVar
@@ -323,20 +349,23 @@ func (c Container) ToContainer() v1.Container {
 		registry = registry.(string) + "/"
 	}
 	container := v1.Container{
-		Image:        registry.(string) + c.Image,
-		Args:         c.Args,
-		Command:      c.Command,
-		Resources:    c.ToResources(),
-		Ports:        c.ToContainerPorts(),
-		WorkingDir:   c.WorkingDir,
-		VolumeMounts: c.K8VolumeMounts,
+		Image:           registry.(string) + c.Image,
+		Resources:       c.ToResources(),
+		Ports:           c.ToContainerPorts(),
+		WorkingDir:      c.WorkingDir,
+		VolumeMounts:    append(append(c.K8VolumeMounts, c.ToVolumeMounts()...), c.ToSecretVolumeMounts()...),
+		EnvFrom:         c.ToSecretEnvFrom(),
+		SecurityContext: c.ToSecurityContext(),
+		ImagePullPolicy: c.ToImagePullPolicy(),
 	}
 
+	container.Command, container.Args = c.ToCommandArgs()
+
 	if len(c.Commands) > 0 {
 		var sh []string
 		sh = []string{"sh", "-c", strings.Join(c.Commands, ";")}
 		container.Lifecycle = &v1.Lifecycle{
-			PostStart: &v1.Handler{
+			PostStart: &v1.LifecycleHandler{
 				Exec: &v1.ExecAction{
 					Command: sh,
 				},
@@ -352,7 +381,10 @@ func (c Container) ToContainer() v1.Container {
 	if c.ReadinessProbe != nil {
 		container.ReadinessProbe = c.ReadinessProbe.ToProbe()
 	}
-	container.Env = c.ToEnvVars()
+	if c.StartupProbe != nil {
+		container.StartupProbe = c.StartupProbe.ToProbe()
+	}
+	container.Env = append(c.ToEnvVars(), c.ToSecretEnvVars()...)
 	return container
 }
 
@@ -366,107 +398,117 @@ func (c Container) ToServiceType() v1.ServiceType {
 	return v1.ServiceTypeClusterIP
 }
 
-func (c Container) ToIngress() v1beta1.Ingress {
-	return v1beta1.Ingress{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "Ingress",
-			APIVersion: "extensions/v1beta1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: c.Service + "-ing",
-		},
-		Spec: v1beta1.IngressSpec{
-			Rules: []v1beta1.IngressRule{
-				{
-					Host: c.Ingress,
-					IngressRuleValue: v1beta1.IngressRuleValue{
-						HTTP: &v1beta1.HTTPIngressRuleValue{
-							Paths: []v1beta1.HTTPIngressPath{
-								{
-									Backend: v1beta1.IngressBackend{
-										ServicePort: intstr.FromInt(c.Ports[0].Published),
-										ServiceName: c.Service,
-									},
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-	}
+func (c Container) ToDeployment() string {
+	return MarshalSpecs(c.ToSpecs(), "yaml")
 }
 
-func (c Container) ToHttpIngressPaths() []v1beta1.HTTPIngressPath {
-	paths := []v1beta1.HTTPIngressPath{}
+// ToSpecs returns all the kubernetes objects required to run the container
+func (c Container) ToSpecs() []interface{} {
+	var specs []interface{}
+	specs = append(specs, c.ToConfigMaps()...)
+	specs = append(specs, c.ToSecrets()...)
+	specs = append(specs, c.ToPersistentVolumeClaims()...)
+	specs = append(specs, c.ToSidecarSpecs()...)
+	specs = append(specs, c.ToRbac()...)
+	specs = append(specs, c.ToWorkload()...)
+	if c.IsAutoscaled() {
+		specs = append(specs, c.ToHorizontalPodAutoscaler())
+	}
+	if c.IsReplicated() {
+		specs = append(specs, c.ToPodDisruptionBudget())
+	}
+	if c.IsNetworkPolicyEnabled() {
+		specs = append(specs, c.ToNetworkPolicy())
+	}
+
+	if len(c.Ports) > 0 {
+		specs = append(specs, c.ToService())
+
+		if len(c.ToIngressRoutes()) > 0 && c.IsGatewayMode() {
+			specs = append(specs, c.ToHTTPRoutes()...)
+		} else if len(c.ToIngressRoutes()) > 0 {
+			specs = append(specs, c.ToIngress())
+		}
 
-	for _, port := range c.Ports {
-		paths = append(paths, v1beta1.HTTPIngressPath{
-			Backend: v1beta1.IngressBackend{
-				ServicePort: intstr.FromInt(port.Published),
-				ServiceName: c.Service,
-			},
-		})
 	}
-	return paths
 
+	return specs
 }
 
-func (c Container) ToDeployment() string {
-	var specs []interface{}
-	specs = append(specs, c.ToConfigMaps()...)
+func (c Container) ToSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"app": c.Service,
+		},
+	}
+}
 
-	specs = append(specs, v1beta1.Deployment{
+func (c Container) ToPodTemplate() v1.PodTemplateSpec {
+	return v1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      c.ToPodLabels(),
+			Annotations: c.ToAnnotations(),
+		},
+		Spec: v1.PodSpec{
+			Hostname:                     c.ToHostname(),
+			Containers:                   append([]v1.Container{c.ToContainer()}, ToContainers(c.Sidecars)...),
+			InitContainers:               ToContainers(c.InitContainers),
+			Volumes:                      c.ToPodVolumes(),
+			Affinity:                     c.ToAffinity(),
+			SecurityContext:              c.ToPodSecurityContext(),
+			NodeSelector:                 c.ToNodeSelector(),
+			Tolerations:                  c.ToTolerations(),
+			TopologySpreadConstraints:    c.ToTopologySpreadConstraints(),
+			PriorityClassName:            c.PriorityClass,
+			RuntimeClassName:             c.ToRuntimeClassName(),
+			ImagePullSecrets:             c.ToImagePullSecrets(),
+			ServiceAccountName:           c.ServiceAccountName(),
+			AutomountServiceAccountToken: c.AutomountToken,
+		},
+	}
+}
+
+func (c Container) ToK8Deployment() appsv1.Deployment {
+	return appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
-			APIVersion: "apps/v1beta2",
+			APIVersion: c.KubeVersion().AppsAPIVersion(),
 			Kind:       "Deployment",
 		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: c.Service,
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: c.ToReplicas(),
+			Selector: c.ToSelector(),
+			Template: c.ToPodTemplate(),
 		},
-		Spec: v1beta1.DeploymentSpec{
-			Replicas: &c.Replicas,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": c.Service,
-				},
-			},
-			Template: v1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": c.Service,
-					},
-				},
-				Spec: v1.PodSpec{
-					Containers: []v1.Container{c.ToContainer()},
-					Volumes:    c.K8Volumes,
-				},
-			},
-		},
-	})
+	}
+}
 
-	if len(c.Ports) > 0 {
-		specs = append(specs, v1.Service{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Service",
-				APIVersion: "v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name: c.Service,
-			},
-			Spec: v1.ServiceSpec{
-				Type: c.ToServiceType(),
-				Selector: map[string]string{
-					"app": c.Service,
-				},
-				Ports: c.ToPorts(),
+func (c Container) ToService() v1.Service {
+	return v1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: v1.ServiceSpec{
+			Type: c.ToServiceType(),
+			Selector: map[string]string{
+				"app": c.Service,
 			},
-		})
-
-		if c.Ingress != "" {
-			specs = append(specs, c.ToIngress())
-		}
+			Ports: c.ToPorts(),
+		},
+	}
+}
 
+// MarshalSpecs serializes specs as a stream of yaml documents or a json v1 List
+func MarshalSpecs(specs []interface{}, format string) string {
+	if format == "json" {
+		data, _ := json.MarshalIndent(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      specs,
+		}, "", "  ")
+		return string(data)
 	}
 
 	out := ""
@@ -478,24 +520,19 @@ func (c Container) ToDeployment() string {
 
 	return out
 }
-func (c Container) ToVolumeMounts() []v1.VolumeMount {
-	var mounts []v1.VolumeMount
-	return mounts
+// KubeVersion returns the kubernetes version that specs are generated for
+func (c Container) KubeVersion() KubeVersion {
+	return ParseKubeVersion(c.Group.Get("kube_version"))
 }
 
 func ConfigMapName(name string) string {
 	return strings.Replace(strings.Replace(name, "/", "", -1), ".", "-", -1)
 }
 
-func (c *Container) ToConfigMaps() []interface{} {
-	var configs []interface{}
-
-	if c.K8Volumes == nil {
-		c.K8Volumes = []v1.Volume{}
-	}
-
-	if c.K8VolumeMounts == nil {
-		c.K8VolumeMounts = []v1.VolumeMount{}
+// ToConfigData reads the contents of all files and renders all templates, keyed by the directory they are mounted in
+func (c Container) ToConfigData() map[string]map[string]string {
+	if c.ConfigData != nil {
+		return c.ConfigData
 	}
 	cms := make(map[string]map[string]string)
 	for _path, file := range c.Files {
@@ -514,15 +551,9 @@ func (c *Container) ToConfigMaps() []interface{} {
 	for _path, file := range c.Templates {
 		dir := path.Dir(_path)
 		data, _ := ioutil.ReadFile("files/" + file)
-		template := ConvertSyntaxFromJinjaToPongo(string(data))
-		tpl, err := pongo2.FromString(template)
-		if err != nil {
-			log.Warnf("Error parsing: %s: %v", template, err)
-			continue
-		}
-		out, err := tpl.Execute(c.Group.Vars)
+		out, err := RenderTemplate(TemplateEngine(file, c.Group.Vars), string(data), c.Group.Vars)
 		if err != nil {
-			log.Warnf("Error parsing: %s: %v", template, err)
+			log.Warnf("Error parsing: %s: %v", file, err)
 			continue
 		}
 
@@ -535,60 +566,47 @@ func (c *Container) ToConfigMaps() []interface{} {
 
 		cm[path.Base(_path)] = string(out)
 	}
+	return cms
+}
 
-	for name, cm := range cms {
+func (c *Container) ToConfigMaps() []interface{} {
+	return c.toConfigMaps("")
+}
+
+// toConfigMaps returns a config map per directory, named after the directory with the prefix
+func (c *Container) toConfigMaps(prefix string) []interface{} {
+	var configs []interface{}
+
+	if c.K8Volumes == nil {
+		c.K8Volumes = []v1.Volume{}
+	}
+
+	if c.K8VolumeMounts == nil {
+		c.K8VolumeMounts = []v1.VolumeMount{}
+	}
+
+	for name, cm := range c.ToConfigData() {
 		c.K8Volumes = append(c.K8Volumes, v1.Volume{
-			Name: ConfigMapName(name),
+			Name: ConfigMapName(prefix + name),
 			VolumeSource: v1.VolumeSource{
 				ConfigMap: &v1.ConfigMapVolumeSource{
 					LocalObjectReference: v1.LocalObjectReference{
-						Name: ConfigMapName(name),
+						Name: ConfigMapName(prefix + name),
 					},
 				},
 			},
 		})
 		c.K8VolumeMounts = append(c.K8VolumeMounts, v1.VolumeMount{
-			Name:      ConfigMapName(name),
+			Name:      ConfigMapName(prefix + name),
 			MountPath: name,
 		})
-		configs = append(configs, NewConfigMap(name, cm))
+		configMap := NewConfigMap(prefix+name, cm)
+		configMap.ObjectMeta = c.ToObjectMeta(configMap.Name)
+		configs = append(configs, configMap)
 	}
 
 	return configs
 }
-func (c HealthCheck) ToProbe() *v1.Probe {
-
-	probe := &v1.Probe{
-		TimeoutSeconds:      c.Timeout,
-		PeriodSeconds:       c.Period,
-		InitialDelaySeconds: c.Delay,
-	}
-
-	if c.Url != "" {
-		probe.Handler = v1.Handler{
-			HTTPGet: &v1.HTTPGetAction{
-				Path: c.Url,
-			},
-		}
-	} else if c.Port > 0 {
-		probe.Handler = v1.Handler{
-			TCPSocket: &v1.TCPSocketAction{
-				Port: intstr.FromInt(c.Port),
-			},
-		}
-	} else if c.Cmd != " " {
-		probe.Handler = v1.Handler{
-			Exec: &v1.ExecAction{
-				Command: []string{c.Cmd},
-			},
-		}
-	} else {
-		return nil
-	}
-
-	return probe
-}
-
 func NewConfigMap(_path string, content map[string]string) v1.ConfigMap {
 	return v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -636,7 +654,8 @@ func NewContainerFromCompose(file string, group Group) []*Container {
 			c.Group = group
 			c.Service = service.Name
 			if len(service.Command) != 0 {
-				c.Command = service.Command
+				// without an entrypoint the compose command only replaces the image command
+				c.Args = service.Command
 			}
 
 			if service.Deploy.Resources.Limits != nil {
@@ -681,6 +700,9 @@ func NewContainerFromCompose(file string, group Group) []*Container {
 			if service.Image != "" {
 				c.Image = service.Image
 			}
+			if len(service.DependsOn) != 0 {
+				c.DependsOn = service.DependsOn
+			}
 			if len(service.Labels) != 0 {
 				c.Labels = service.Labels
 			}
@@ -703,9 +725,15 @@ func NewContainerFromCompose(file string, group Group) []*Container {
 			if service.User != "" {
 				c.User = service.User
 			}
-			//if len(service.Volumes) != 0 {
-			//	c.Volumes = service.Volumes
-			//}
+			if service.ReadOnly {
+				c.ReadOnlyRootFs = &service.ReadOnly
+			}
+			if len(service.CapAdd) != 0 || len(service.CapDrop) != 0 {
+				c.Capabilities = &Capabilities{Add: service.CapAdd, Drop: service.CapDrop}
+			}
+			for _, volume := range service.Volumes {
+				c.Volumes = append(c.Volumes, NewVolumeFromCompose(volume))
+			}
 			if service.WorkingDir != "" {
 				c.WorkingDir = service.WorkingDir
 			}
@@ -4,8 +4,9 @@ import (
 	"strings"
 	log "github.com/sirupsen/logrus"
 
-	"github.com/levigross/grequests"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 	"sort"
 )
@@ -30,11 +31,8 @@ func LatestToTagHarbor(c *Container) {
 	if c.Group.Get("latest_to_tag_harbor") == "all" {
 		all = true
 	}
-	var tag string
-	image := strings.Split(c.Image, ":")[0]
-	if strings.Contains(c.Image, ":") {
-		tag = strings.Split(c.Image, ":")[1]
-	} else {
+	image, tag, digest := ParseImage(c.Image)
+	if tag == "" && digest == "" {
 		tag = "latest"
 	}
 
@@ -61,12 +59,13 @@ func LatestToTagHarbor(c *Container) {
 		api := fmt.Sprintf("https://%s/api/repositories/%s/%s/tags", host, project, image)
 		log.Debugf("[%s] Looking up latest using %s", image, api)
 
-		response, err := grequests.Get(api, nil)
+		response, err := http.Get(api)
 		if err != nil {
 			panic(err)
 		}
 		imgs := []HarborImage{}
-		response.JSON(&imgs)
+		json.NewDecoder(response.Body).Decode(&imgs)
+		response.Body.Close()
 
 		if len(imgs) == 0 {
 			log.Errorf("[%s] No tags found", c.ImageName)
@@ -80,6 +79,7 @@ func LatestToTagHarbor(c *Container) {
 			}
 			log.Debugf("[%s] Found tag %s created %s", image, tag.Name, tag.Created)
 			c.ImageTag = tag.Name
+			c.ImageDigest = ""
 			c.Image = c.ImageName + ":" + c.ImageTag
 		}
 	}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DefaultMaxUnavailable is used when neither min_available nor max_unavailable is specified
+var DefaultMaxUnavailable = intstr.FromInt(1)
+
+// IsReplicated returns true for deployments and statefulsets that can run more than 1 replica
+func (c Container) IsReplicated() bool {
+	if c.Kind != DeploymentKind && c.Kind != StatefulSetKind {
+		return false
+	}
+	if c.IsAutoscaled() {
+		return c.Autoscale.Max > 1
+	}
+	return c.Replicas > 1
+}
+
+// ToPodDisruptionBudget returns a policy/v1 PodDisruptionBudget, or the legacy policy/v1beta1 equivalent for
+// clusters older than 1.21
+func (c Container) ToPodDisruptionBudget() interface{} {
+	pdb := policyv1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector:       c.ToSelector(),
+			MinAvailable:   c.MinAvailable,
+			MaxUnavailable: c.MaxUnavailable,
+		},
+	}
+	if pdb.Spec.MinAvailable == nil && pdb.Spec.MaxUnavailable == nil {
+		maxUnavailable := DefaultMaxUnavailable
+		pdb.Spec.MaxUnavailable = &maxUnavailable
+	}
+
+	version := c.KubeVersion()
+	if version.AtLeast(21) {
+		return pdb
+	}
+	if !version.AtLeast(7) {
+		log.Fatalf("[%s] PodDisruptionBudgets require kube_version 1.7 or later, got %s", c.Service, version)
+	}
+	legacy := ToLegacyPodDisruptionBudget(pdb)
+	legacy.APIVersion = version.PolicyAPIVersion()
+	return legacy
+}
+
+// ToLegacyPodDisruptionBudget converts a PodDisruptionBudget to the policy/v1beta1 schema
+func ToLegacyPodDisruptionBudget(pdb policyv1.PodDisruptionBudget) policyv1beta1.PodDisruptionBudget {
+	return policyv1beta1.PodDisruptionBudget{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "policy/v1beta1",
+			Kind:       "PodDisruptionBudget",
+		},
+		ObjectMeta: pdb.ObjectMeta,
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector:       pdb.Spec.Selector,
+			MinAvailable:   pdb.Spec.MinAvailable,
+			MaxUnavailable: pdb.Spec.MaxUnavailable,
+		},
+	}
+}
+
+// ToSpreadAffinityTerm prefers spreading the replicas of a service across nodes
+func (c Container) ToSpreadAffinityTerm() v1.WeightedPodAffinityTerm {
+	return v1.WeightedPodAffinityTerm{
+		Weight: 100,
+		PodAffinityTerm: v1.PodAffinityTerm{
+			LabelSelector: c.ToSelector(),
+			TopologyKey:   v1.LabelHostname,
+		},
+	}
+}
+
+// applyDisruptionDefaults uses the group budget unless the container specifies its own
+func (c *Container) applyDisruptionDefaults(defaults ContainerDefaults) {
+	if c.MinAvailable == nil && c.MaxUnavailable == nil {
+		c.MinAvailable = defaults.MinAvailable
+		c.MaxUnavailable = defaults.MaxUnavailable
+	}
+	if c.MinAvailable != nil && c.MaxUnavailable != nil {
+		log.Errorf("[%s] Only one of min_available and max_unavailable can be specified, using min_available", c.Service)
+		c.MaxUnavailable = nil
+	}
+}
@@ -1,28 +1,121 @@
 package pkg
 
 import (
+	"fmt"
 	"github.com/flosch/pongo2"
-	. "github.com/flosch/pongo2"
+	"io/ioutil"
+	"os"
 	"path"
+	"sync"
+)
+
+// Filter transforms in using an optional param, the same filter is available to the jinja
+// engine as {{ in | name(param) }} and to the go engine as {{ .in | name param }}
+type Filter func(in interface{}, param interface{}) (interface{}, error)
+
+// Lookup resolves terms into a value, it is called from either engine using lookup('name', terms...)
+type Lookup func(terms ...interface{}) (interface{}, error)
+
+var (
+	registry = sync.RWMutex{}
+	filters  = make(map[string]Filter)
+	lookups  = make(map[string]Lookup)
 )
 
 func init() {
-	pongo2.RegisterFilter("basename", basename)
-	pongo2.RegisterFilter("dirname", dirname)
+	RegisterFilter("basename", basename)
+	RegisterFilter("dirname", dirname)
+	RegisterLookup("env", env)
+	RegisterLookup("file", file)
+	pongo2.Globals["lookup"] = LookupValue
+}
+
+// RegisterFilter makes a filter available to all templates, replacing any existing filter with the same name
+func RegisterFilter(name string, filter Filter) {
+	registry.Lock()
+	filters[name] = filter
+	registry.Unlock()
 
+	fn := func(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+		out, err := filter(in.Interface(), param.Interface())
+		if err != nil {
+			return nil, &pongo2.Error{Sender: "filter:" + name, OrigError: err}
+		}
+		return pongo2.AsValue(out), nil
+	}
+	if pongo2.FilterExists(name) {
+		pongo2.ReplaceFilter(name, fn)
+	} else {
+		pongo2.RegisterFilter(name, fn)
+	}
 }
 
-func basename(in *Value, param *Value) (*Value, *Error) {
-	if in.IsNil() {
-		return param, nil
+// RegisterLookup makes a lookup available to all templates, replacing any existing lookup with the same name
+func RegisterLookup(name string, lookup Lookup) {
+	registry.Lock()
+	defer registry.Unlock()
+	lookups[name] = lookup
+}
+
+// LookupValue calls the lookup registered under name
+func LookupValue(name string, terms ...interface{}) (interface{}, error) {
+	registry.RLock()
+	lookup, ok := lookups[name]
+	registry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown lookup %s", name)
+	}
+	return lookup(terms...)
+}
+
+// goFilters adapts the registered filters to go template functions, where a piped value is passed as the last argument
+func goFilters() map[string]interface{} {
+	registry.RLock()
+	defer registry.RUnlock()
+	funcs := make(map[string]interface{})
+	for name, filter := range filters {
+		filter := filter
+		funcs[name] = func(args ...interface{}) (interface{}, error) {
+			switch len(args) {
+			case 1:
+				return filter(args[0], nil)
+			case 2:
+				return filter(args[1], args[0])
+			}
+			return nil, fmt.Errorf("expected 1 or 2 arguments, got %d", len(args))
+		}
 	}
+	return funcs
+}
 
-	return AsValue(path.Base(in.String())), nil
+func basename(in interface{}, param interface{}) (interface{}, error) {
+	if in == nil {
+		return param, nil
+	}
+	return path.Base(fmt.Sprintf("%v", in)), nil
 }
 
-func dirname(in *Value, param *Value) (*Value, *Error) {
-	if in.IsNil() {
+func dirname(in interface{}, param interface{}) (interface{}, error) {
+	if in == nil {
 		return param, nil
 	}
-	return AsValue(path.Dir(in.String())), nil
+	return path.Dir(fmt.Sprintf("%v", in)), nil
+}
+
+func env(terms ...interface{}) (interface{}, error) {
+	if len(terms) != 1 {
+		return nil, fmt.Errorf("env lookup expects a single variable name")
+	}
+	return os.Getenv(fmt.Sprintf("%v", terms[0])), nil
+}
+
+func file(terms ...interface{}) (interface{}, error) {
+	if len(terms) != 1 {
+		return nil, fmt.Errorf("file lookup expects a single path")
+	}
+	data, err := ioutil.ReadFile(fmt.Sprintf("%v", terms[0]))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
 }
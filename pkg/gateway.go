@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GatewayMode selects HTTPRoutes instead of Ingresses with ingress_mode
+const GatewayMode = "gateway"
+
+// HTTPRoute and the types below are the subset of gateway.networking.k8s.io/v1 used by smarti,
+// the gateway api types are not part of k8s.io/api
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              HTTPRouteSpec `json:"spec"`
+}
+
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+type ParentReference struct {
+	Name        string  `json:"name"`
+	Namespace   *string `json:"namespace,omitempty"`
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch   `json:"matches,omitempty"`
+	BackendRefs []HTTPBackendRef   `json:"backendRefs,omitempty"`
+	Timeouts    *HTTPRouteTimeouts `json:"timeouts,omitempty"`
+}
+
+type HTTPRouteMatch struct {
+	Path    *HTTPPathMatch    `json:"path,omitempty"`
+	Headers []HTTPHeaderMatch `json:"headers,omitempty"`
+}
+
+type HTTPPathMatch struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type HTTPHeaderMatch struct {
+	Type  string `json:"type,omitempty"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HTTPBackendRef struct {
+	Name   string `json:"name"`
+	Port   *int32 `json:"port,omitempty"`
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+type HTTPRouteTimeouts struct {
+	Request string `json:"request,omitempty"`
+}
+
+// RouteBackend splits the traffic of a gateway route between services, e.g. for canary releases
+type RouteBackend struct {
+	Service string `json:"service,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	Weight  *int32 `json:"weight,omitempty"`
+}
+
+var gatewayDuration = regexp.MustCompile("^([0-9]{1,5}(h|m|s|ms)){1,4}$")
+
+// IsGatewayMode returns true when routes are exposed with HTTPRoutes, ingress_mode can be set on the
+// container, in container_defaults or as a variable
+func (c Container) IsGatewayMode() bool {
+	mode := c.IngressMode
+	if mode == "" {
+		mode = c.Group.Get("ingress_mode")
+	}
+	return strings.ToLower(mode) == GatewayMode
+}
+
+// ToParentReference parses gateway in the [namespace/]name[:section] form
+func (c Container) ToParentReference() ParentReference {
+	gateway := c.Gateway
+	if gateway == "" {
+		gateway = c.Group.Get("gateway")
+	}
+	if gateway == "" {
+		log.Errorf("[%s] Missing gateway for ingress_mode gateway", c.Service)
+	}
+	ref := ParentReference{}
+	if i := strings.Index(gateway, ":"); i > 0 {
+		section := gateway[i+1:]
+		ref.SectionName = &section
+		gateway = gateway[:i]
+	}
+	if i := strings.Index(gateway, "/"); i > 0 {
+		namespace := gateway[:i]
+		ref.Namespace = &namespace
+		gateway = gateway[i+1:]
+	}
+	ref.Name = gateway
+	return ref
+}
+
+// ToPathMatchType maps path_type to the gateway api path match types, defaulting to PathPrefix
+func ToPathMatchType(pathType string) string {
+	switch strings.ToLower(pathType) {
+	case "exact":
+		return "Exact"
+	case "regex", "regularexpression", "regular_expression":
+		return "RegularExpression"
+	}
+	return "PathPrefix"
+}
+
+func (c Container) ToHTTPRouteRule(route IngressRoute) HTTPRouteRule {
+	match := HTTPRouteMatch{
+		Path: &HTTPPathMatch{
+			Type:  ToPathMatchType(route.PathType),
+			Value: route.Path,
+		},
+	}
+	for _, name := range sortedKeys(route.Headers) {
+		match.Headers = append(match.Headers, HTTPHeaderMatch{
+			Type:  "Exact",
+			Name:  name,
+			Value: route.Headers[name],
+		})
+	}
+
+	backends := route.Backends
+	if len(backends) == 0 {
+		backends = []RouteBackend{{Weight: route.Weight}}
+	}
+	rule := HTTPRouteRule{Matches: []HTTPRouteMatch{match}}
+	for _, backend := range backends {
+		if backend.Service == "" {
+			backend.Service = c.Service
+		}
+		if backend.Port == 0 {
+			backend.Port = route.Port
+		}
+		port := int32(backend.Port)
+		rule.BackendRefs = append(rule.BackendRefs, HTTPBackendRef{
+			Name:   backend.Service,
+			Port:   &port,
+			Weight: backend.Weight,
+		})
+	}
+
+	if gatewayDuration.MatchString(route.Timeout) {
+		rule.Timeouts = &HTTPRouteTimeouts{Request: route.Timeout}
+	} else if route.Timeout != "" {
+		log.Warnf("[%s] Ignoring invalid timeout %s for %s%s, use e.g. 30s or 1m30s", c.Service, route.Timeout, route.Host, route.Path)
+	}
+	return rule
+}
+
+// ToHTTPRoutes returns a route per host, as hostnames apply to all the rules of a route
+func (c Container) ToHTTPRoutes() []interface{} {
+	rules := make(map[string][]HTTPRouteRule)
+	for _, route := range c.ToIngressRoutes() {
+		rules[route.Host] = append(rules[route.Host], c.ToHTTPRouteRule(route))
+	}
+
+	var routes []interface{}
+	for _, host := range c.ToIngressHosts() {
+		name := c.Service
+		if len(rules) > 1 {
+			name = c.Service + "-" + strings.Replace(host, ".", "-", -1)
+		}
+		hostRules := rules[host]
+		// gateways match the most specific path first, ordering is only kept for readability
+		sort.SliceStable(hostRules, func(i, j int) bool {
+			return hostRules[i].Matches[0].Path.Value > hostRules[j].Matches[0].Path.Value
+		})
+		route := HTTPRoute{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "gateway.networking.k8s.io/v1",
+				Kind:       "HTTPRoute",
+			},
+			ObjectMeta: c.ToObjectMeta(name),
+			Spec: HTTPRouteSpec{
+				ParentRefs: []ParentReference{c.ToParentReference()},
+				Hostnames:  []string{host},
+				Rules:      hostRules,
+			},
+		}
+		route.Annotations = mergeStrings(route.Annotations, c.IngressAnnotations)
+		routes = append(routes, route)
+	}
+	return routes
+}
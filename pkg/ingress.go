@@ -0,0 +1,283 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"strings"
+)
+
+// IngressRoute exposes a published port on a host and path, the port defaults to the first port
+// and tls_secret overrides the tls_secret of the container for the host, headers, weight, backends and timeout
+// are only supported in gateway mode
+type IngressRoute struct {
+	Host      string            `json:"host,omitempty"`
+	Path      string            `json:"path,omitempty"`
+	PathType  string            `json:"path_type,omitempty"`
+	TLSSecret string            `json:"tls_secret,omitempty"`
+	Port      int               `json:"port,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Weight    *int32            `json:"weight,omitempty"`
+	Backends  []RouteBackend    `json:"backends,omitempty"`
+	Timeout   string            `json:"timeout,omitempty"`
+}
+
+// applyIngressDefaults fills in the group ingress settings, each of the ingress_domains adds a <service>.<domain> host
+func (c *Container) applyIngressDefaults(defaults ContainerDefaults) {
+	if c.Ingress == "" && defaults.Ingress != "" {
+		c.Ingress = c.Service + "." + defaults.Ingress
+	}
+	if len(c.Ingresses) == 0 {
+		for _, domain := range defaults.IngressDomains {
+			c.Ingresses = append(c.Ingresses, IngressRoute{Host: c.Service + "." + domain})
+		}
+	}
+	if c.IngressClass == "" {
+		c.IngressClass = defaults.IngressClass
+	}
+	if c.TLSSecret == "" {
+		c.TLSSecret = defaults.TLSSecret
+	}
+	if c.CertIssuer == "" {
+		c.CertIssuer = defaults.CertIssuer
+	}
+	if c.IngressMode == "" {
+		c.IngressMode = defaults.IngressMode
+	}
+	if c.Gateway == "" {
+		c.Gateway = defaults.Gateway
+	}
+	c.IngressAnnotations = mergeStrings(defaults.IngressAnnotations, c.IngressAnnotations)
+
+	for _, route := range c.Ingresses {
+		if route.Host == "" {
+			log.Errorf("[%s] Missing host for ingress %s", c.Service, route.Path)
+		}
+		if route.Port != 0 && !c.hasPublishedPort(route.Port) {
+			log.Warnf("[%s] Ingress %s%s uses port %d which is not published", c.Service, route.Host, route.Path, route.Port)
+		}
+		if !c.IsGatewayMode() && (len(route.Headers) > 0 || route.Weight != nil || len(route.Backends) > 0 || route.Timeout != "") {
+			log.Warnf("[%s] Ingress %s%s uses headers, weights or timeouts which require ingress_mode gateway", c.Service, route.Host, route.Path)
+		}
+	}
+}
+
+// ToIngressRoutes returns the ingress host followed by the ingresses, only services with ports can be exposed
+func (c Container) ToIngressRoutes() []IngressRoute {
+	if len(c.Ports) == 0 {
+		return nil
+	}
+	var candidates, routes []IngressRoute
+	if c.Ingress != "" {
+		candidates = append(candidates, IngressRoute{Host: c.Ingress})
+	}
+	for _, route := range append(candidates, c.Ingresses...) {
+		if route.Path == "" {
+			route.Path = "/"
+		}
+		if route.Port == 0 {
+			route.Port = c.Ports[0].Published
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func (c Container) hasPublishedPort(port int) bool {
+	for _, p := range c.Ports {
+		if p.Published == port {
+			return true
+		}
+	}
+	return false
+}
+
+// ToIngressHosts returns the unique hosts of the ingress routes in order
+func (c Container) ToIngressHosts() []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, route := range c.ToIngressRoutes() {
+		if !seen[route.Host] {
+			seen[route.Host] = true
+			hosts = append(hosts, route.Host)
+		}
+	}
+	return hosts
+}
+
+// ToIngressTLS groups the hosts by the tls_secret of their route, defaulting to the tls_secret of the container,
+// or a <service>-tls secret issued by cert-manager when cert_issuer is specified
+func (c Container) ToIngressTLS() []networkingv1.IngressTLS {
+	var tls []networkingv1.IngressTLS
+	secrets := make(map[string]int)
+	seen := make(map[string]bool)
+	for _, route := range c.ToIngressRoutes() {
+		secret := route.TLSSecret
+		if secret == "" {
+			secret = c.TLSSecret
+		}
+		if secret == "" && c.CertIssuer != "" {
+			secret = c.Service + "-tls"
+		}
+		if secret == "" || seen[route.Host] {
+			continue
+		}
+		seen[route.Host] = true
+		if i, ok := secrets[secret]; ok {
+			tls[i].Hosts = append(tls[i].Hosts, route.Host)
+			continue
+		}
+		secrets[secret] = len(tls)
+		tls = append(tls, networkingv1.IngressTLS{
+			Hosts:      []string{route.Host},
+			SecretName: secret,
+		})
+	}
+	return tls
+}
+
+// ToIngress returns a networking.k8s.io/v1 Ingress, or the legacy v1beta1 equivalent for clusters older than 1.19
+func (c Container) ToIngress() interface{} {
+	ingress := networkingv1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service + "-ing"),
+		Spec: networkingv1.IngressSpec{
+			TLS: c.ToIngressTLS(),
+		},
+	}
+	ingress.Annotations = mergeStrings(ingress.Annotations, c.IngressAnnotations)
+	if c.CertIssuer != "" {
+		ingress.Annotations = mergeStrings(ingress.Annotations, map[string]string{"cert-manager.io/cluster-issuer": c.CertIssuer})
+	}
+	if c.IngressClass != "" {
+		ingress.Spec.IngressClassName = &c.IngressClass
+	}
+	for _, host := range c.ToIngressHosts() {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: c.ToHttpIngressPaths(host),
+				},
+			},
+		})
+	}
+
+	version := c.KubeVersion()
+	if version.AtLeast(19) {
+		return ingress
+	}
+	legacy := ToLegacyIngress(ingress, version)
+	legacy.APIVersion = version.IngressAPIVersion()
+	return legacy
+}
+
+// ToPathType defaults to Prefix
+func ToPathType(pathType string) networkingv1.PathType {
+	switch strings.ToLower(pathType) {
+	case "exact":
+		return networkingv1.PathTypeExact
+	case "implementationspecific", "implementation-specific", "implementation_specific":
+		return networkingv1.PathTypeImplementationSpecific
+	}
+	return networkingv1.PathTypePrefix
+}
+
+func (c Container) ToHttpIngressPath(path string, port int) networkingv1.HTTPIngressPath {
+	pathType := networkingv1.PathTypePrefix
+	return networkingv1.HTTPIngressPath{
+		Path:     path,
+		PathType: &pathType,
+		Backend: networkingv1.IngressBackend{
+			Service: &networkingv1.IngressServiceBackend{
+				Name: c.Service,
+				Port: networkingv1.ServiceBackendPort{
+					Number: int32(port),
+				},
+			},
+		},
+	}
+}
+
+// ToHttpIngressPaths returns the paths of the routes for host
+func (c Container) ToHttpIngressPaths(host string) []networkingv1.HTTPIngressPath {
+	paths := []networkingv1.HTTPIngressPath{}
+
+	for _, route := range c.ToIngressRoutes() {
+		if route.Host != host {
+			continue
+		}
+		path := c.ToHttpIngressPath(route.Path, route.Port)
+		pathType := ToPathType(route.PathType)
+		path.PathType = &pathType
+		paths = append(paths, path)
+	}
+	return paths
+
+}
+
+// ToLegacyIngress converts an Ingress to the v1beta1 schema used by networking.k8s.io/v1beta1 and extensions/v1beta1,
+// path types are kept for 1.18 and later
+func ToLegacyIngress(ingress networkingv1.Ingress, version KubeVersion) v1beta1.Ingress {
+	legacy := v1beta1.Ingress{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Ingress",
+			APIVersion: "networking.k8s.io/v1beta1",
+		},
+		ObjectMeta: ingress.ObjectMeta,
+	}
+
+	if ingress.Spec.IngressClassName != nil {
+		// spec.ingressClassName was only added in 1.18, the annotation works everywhere
+		if legacy.Annotations == nil {
+			legacy.Annotations = make(map[string]string)
+		}
+		legacy.Annotations["kubernetes.io/ingress.class"] = *ingress.Spec.IngressClassName
+	}
+
+	for _, tls := range ingress.Spec.TLS {
+		legacy.Spec.TLS = append(legacy.Spec.TLS, v1beta1.IngressTLS{
+			Hosts:      tls.Hosts,
+			SecretName: tls.SecretName,
+		})
+	}
+
+	for _, rule := range ingress.Spec.Rules {
+		legacyRule := v1beta1.IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			legacyRule.HTTP = &v1beta1.HTTPIngressRuleValue{}
+			for _, path := range rule.HTTP.Paths {
+				legacyPath := v1beta1.HTTPIngressPath{
+					Path:    path.Path,
+					Backend: toLegacyIngressBackend(path.Backend),
+				}
+				if path.PathType != nil && version.AtLeast(18) {
+					pathType := v1beta1.PathType(*path.PathType)
+					legacyPath.PathType = &pathType
+				}
+				legacyRule.HTTP.Paths = append(legacyRule.HTTP.Paths, legacyPath)
+			}
+		}
+		legacy.Spec.Rules = append(legacy.Spec.Rules, legacyRule)
+	}
+	return legacy
+}
+
+func toLegacyIngressBackend(backend networkingv1.IngressBackend) v1beta1.IngressBackend {
+	if backend.Service == nil {
+		return v1beta1.IngressBackend{}
+	}
+	port := intstr.FromInt(int(backend.Service.Port.Number))
+	if backend.Service.Port.Name != "" {
+		port = intstr.FromString(backend.Service.Port.Name)
+	}
+	return v1beta1.IngressBackend{
+		ServiceName: backend.Service.Name,
+		ServicePort: port,
+	}
+}
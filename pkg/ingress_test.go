@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToIngressTLS(t *testing.T) {
+	ports := []ContainerPort{{Published: 80, Target: 8080}}
+	tests := []struct {
+		name      string
+		container Container
+		expected  []networkingv1.IngressTLS
+	}{
+		{"no tls", Container{Service: "web", Ports: ports, Ingress: "web.example.com"}, nil},
+		{"container secret", Container{Service: "web", Ports: ports, Ingress: "web.example.com", TLSSecret: "wildcard",
+			Ingresses: []IngressRoute{{Host: "www.example.com"}, {Host: "web.example.com", Path: "/api"}}},
+			[]networkingv1.IngressTLS{{Hosts: []string{"web.example.com", "www.example.com"}, SecretName: "wildcard"}}},
+		{"route secrets", Container{Service: "web", Ports: ports, Ingress: "web.example.com", TLSSecret: "wildcard",
+			Ingresses: []IngressRoute{{Host: "web.example.org", TLSSecret: "org"}, {Host: "www.example.com"}, {Host: "www.example.org", TLSSecret: "org"}}},
+			[]networkingv1.IngressTLS{
+				{Hosts: []string{"web.example.com", "www.example.com"}, SecretName: "wildcard"},
+				{Hosts: []string{"web.example.org", "www.example.org"}, SecretName: "org"},
+			}},
+		{"cert issuer", Container{Service: "web", Ports: ports, Ingress: "web.example.com", CertIssuer: "letsencrypt",
+			Ingresses: []IngressRoute{{Host: "web.example.org", TLSSecret: "org"}}},
+			[]networkingv1.IngressTLS{
+				{Hosts: []string{"web.example.com"}, SecretName: "web-tls"},
+				{Hosts: []string{"web.example.org"}, SecretName: "org"},
+			}},
+		{"no ports", Container{Service: "web", Ingress: "web.example.com", TLSSecret: "wildcard"}, nil},
+	}
+	for _, test := range tests {
+		if tls := test.container.ToIngressTLS(); !reflect.DeepEqual(tls, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, tls)
+		}
+	}
+}
+
+func TestToLegacyIngress(t *testing.T) {
+	class := "nginx"
+	c := Container{Service: "web", Ports: []ContainerPort{{Published: 80, Target: 8080}}}
+	path := c.ToHttpIngressPath("/", 80)
+	ingress := networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-ing"},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &class,
+			TLS:              []networkingv1.IngressTLS{{Hosts: []string{"web.example.com"}, SecretName: "web-tls"}},
+			Rules: []networkingv1.IngressRule{{
+				Host: "web.example.com",
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{Paths: []networkingv1.HTTPIngressPath{path}},
+				},
+			}},
+		},
+	}
+	tests := []struct {
+		version  string
+		pathType bool
+	}{
+		{"1.14", false},
+		{"1.17", false},
+		{"1.18", true},
+	}
+	for _, test := range tests {
+		legacy := ToLegacyIngress(ingress, ParseKubeVersion(test.version))
+		if class := legacy.Annotations["kubernetes.io/ingress.class"]; class != "nginx" {
+			t.Errorf("%s: expected the nginx ingress class annotation, got %q", test.version, class)
+		}
+		if len(legacy.Spec.TLS) != 1 || legacy.Spec.TLS[0].SecretName != "web-tls" {
+			t.Errorf("%s: expected the web-tls secret, got %v", test.version, legacy.Spec.TLS)
+		}
+		legacyPath := legacy.Spec.Rules[0].HTTP.Paths[0]
+		if legacyPath.Backend.ServiceName != "web" || legacyPath.Backend.ServicePort.IntValue() != 80 {
+			t.Errorf("%s: expected the web:80 backend, got %v", test.version, legacyPath.Backend)
+		}
+		if (legacyPath.PathType != nil) != test.pathType {
+			t.Errorf("%s: expected path type %v, got %v", test.version, test.pathType, legacyPath.PathType)
+		}
+	}
+	if ingress.Annotations != nil {
+		t.Errorf("expected the ingress to be unchanged, got %v", ingress.Annotations)
+	}
+}
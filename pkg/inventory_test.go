@@ -0,0 +1,40 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+// testInventory parses and post processes containers like an inventory directory would, groups maps the
+// group names to the yaml of their variables
+func testInventory(t *testing.T, groups map[string]string) Inventory {
+	inv := NewInventory()
+	if _, ok := groups["all"]; !ok {
+		inv.AddGroup(Group{Name: "all"})
+	}
+	for name, data := range groups {
+		vars := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(data), &vars); err != nil {
+			t.Fatalf("invalid vars for %s: %v", name, err)
+		}
+		inv.AddGroup(Group{Name: name, Vars: vars})
+	}
+	ParseContainers(inv)
+	inv.Merge()
+	for _, c := range inv.Containers() {
+		c.PostProcess()
+	}
+	return inv
+}
+
+// testContainer returns the container for service
+func testContainer(t *testing.T, inv Inventory, service string) *Container {
+	for _, c := range inv.Containers() {
+		if c.Service == service {
+			return c
+		}
+	}
+	t.Fatalf("missing container %s", service)
+	return nil
+}
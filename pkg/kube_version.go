@@ -0,0 +1,103 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"strings"
+)
+
+// KubeVersion is the major.minor version of the cluster that specs are generated for
+type KubeVersion struct {
+	Major int
+	Minor int
+}
+
+// LatestKubeVersion is assumed when no kube_version is specified
+var LatestKubeVersion = KubeVersion{Major: 1, Minor: 34}
+
+// ParseKubeVersion parses versions such as 1.22, v1.22 or v1.22.3
+func ParseKubeVersion(version string) KubeVersion {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if version == "" {
+		return LatestKubeVersion
+	}
+	parts := strings.Split(version, ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) < 2 {
+		log.Warnf("Invalid kube_version %s, using %s", version, LatestKubeVersion)
+		return LatestKubeVersion
+	}
+	minor, err := strconv.Atoi(strings.TrimSuffix(parts[1], "+"))
+	if err != nil {
+		log.Warnf("Invalid kube_version %s, using %s", version, LatestKubeVersion)
+		return LatestKubeVersion
+	}
+	return KubeVersion{Major: major, Minor: minor}
+}
+
+func (v KubeVersion) String() string {
+	return strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor)
+}
+
+// AtLeast returns true if the version is 1.minor or later
+func (v KubeVersion) AtLeast(minor int) bool {
+	return v.Major > 1 || (v.Major == 1 && v.Minor >= minor)
+}
+
+// AppsAPIVersion is the API version of deployments and daemonsets
+func (v KubeVersion) AppsAPIVersion() string {
+	switch {
+	case v.AtLeast(9):
+		return "apps/v1"
+	case v.AtLeast(8):
+		return "apps/v1beta2"
+	}
+	return "extensions/v1beta1"
+}
+
+// StatefulSetAPIVersion is the API version of statefulsets, which were never served by extensions/v1beta1
+func (v KubeVersion) StatefulSetAPIVersion() string {
+	switch {
+	case v.AtLeast(9):
+		return "apps/v1"
+	case v.AtLeast(8):
+		return "apps/v1beta2"
+	}
+	return "apps/v1beta1"
+}
+
+func (v KubeVersion) IngressAPIVersion() string {
+	switch {
+	case v.AtLeast(19):
+		return "networking.k8s.io/v1"
+	case v.AtLeast(14):
+		return "networking.k8s.io/v1beta1"
+	}
+	return "extensions/v1beta1"
+}
+
+func (v KubeVersion) CronJobAPIVersion() string {
+	if v.AtLeast(21) {
+		return "batch/v1"
+	}
+	return "batch/v1beta1"
+}
+
+// JobAPIVersion is the API version of jobs, batch/v1 is served by every supported version
+func (v KubeVersion) JobAPIVersion() string {
+	return "batch/v1"
+}
+
+func (v KubeVersion) AutoscalingAPIVersion() string {
+	if v.AtLeast(23) {
+		return "autoscaling/v2"
+	}
+	return "autoscaling/v2beta2"
+}
+
+func (v KubeVersion) PolicyAPIVersion() string {
+	if v.AtLeast(21) {
+		return "policy/v1"
+	}
+	return "policy/v1beta1"
+}
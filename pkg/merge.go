@@ -1,7 +1,7 @@
 package pkg
 
 import (
-	"github.com/flosch/pongo2"
+	"regexp"
 	"strings"
 	"reflect"
 	log "github.com/sirupsen/logrus"
@@ -46,26 +46,38 @@ func ToGenericMap(m map[string]string) map[string]interface{} {
 	return out
 }
 
+var lookupCall = regexp.MustCompile(`lookup\([^()]*\)`)
+
+var templateTag = regexp.MustCompile(`(?s){{.*?}}|{%.*?%}`)
+
+// ConvertSyntaxFromJinjaToPongo converts the filter syntax inside template tags, text outside of tags is kept as is
 func ConvertSyntaxFromJinjaToPongo(template string) string {
+	return templateTag.ReplaceAllStringFunc(template, convertTag)
+}
+
+func convertTag(template string) string {
+	// lookup(...) is a function call in both syntaxes, so it is set aside before converting filters
+	calls := lookupCall.FindAllString(template, -1)
+	template = lookupCall.ReplaceAllString(template, "\x00")
 	// jinja used filter(arg), pongo uses filter:arg
 	template = strings.Replace(template, "(", ":", -1)
 	template = strings.Replace(template, ")", "", -1)
+	for _, call := range calls {
+		template = strings.Replace(template, "\x00", call, 1)
+	}
 	return template
 }
 
-func InterpolateString(template string, vars map[string]interface{}) string {
-	if strings.Contains(template, "lookup(") {
-		log.Warningf("ansible lookups not supported %s", template)
-		return template
-	}
+// IsTemplate returns true if s contains template tags
+func IsTemplate(s string) bool {
+	return strings.Contains(s, "{{") || strings.Contains(s, "{%")
+}
 
-	template = ConvertSyntaxFromJinjaToPongo(template)
-	tpl, err := pongo2.FromString(template)
-	if err != nil {
-		log.Debugf("Error parsing: %s: %v", template, err)
+func InterpolateString(template string, vars map[string]interface{}) string {
+	if !IsTemplate(template) {
 		return template
 	}
-	out, err := tpl.Execute(vars)
+	out, err := RenderTemplate(TemplateEngine("", vars), template, vars)
 	if err != nil {
 		log.Debugf("Error parsing: %s: %v", template, err)
 		return template
@@ -118,6 +130,22 @@ func PutAll(src map[string]interface{}, dst map[string]interface{}) {
 	}
 }
 
+// MergeAll is like PutAll, except that dictionaries present in both src and dst are merged recursively
+func MergeAll(src map[string]interface{}, dst map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			merged := make(map[string]interface{})
+			PutAll(dstMap, merged)
+			MergeAll(srcMap, merged)
+			dst[k] = merged
+		} else {
+			dst[k] = v
+		}
+	}
+}
+
 func InterpolateGroups(groups map[string]*Group) {
 	for _, group := range groups {
 		for key, value := range group.Vars {
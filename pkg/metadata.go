@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"regexp"
+	"strings"
+)
+
+// applyMetadataDefaults merges the group labels and annotations, the container's own values take precedence
+func (c *Container) applyMetadataDefaults(defaults ContainerDefaults) {
+	c.Labels = mergeStrings(defaults.Labels, c.Labels)
+	c.Annotations = mergeStrings(defaults.Annotations, c.Annotations)
+	if app, ok := c.Labels["app"]; ok && app != c.Service {
+		log.Warnf("[%s] Ignoring label app=%s, it is used to select the pods of the service", c.Service, app)
+		delete(c.Labels, "app")
+	}
+	c.validateLabels()
+}
+
+// validateLabels moves labels with values that are not valid label values, such as the routing rules of compose
+// labels, to the annotations and drops labels with invalid keys
+func (c *Container) validateLabels() {
+	for _, key := range sortedKeys(c.Labels) {
+		value := c.Labels[key]
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			log.Warnf("[%s] Ignoring label %s: %s", c.Service, key, strings.Join(errs, ", "))
+			delete(c.Labels, key)
+		} else if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			log.Warnf("[%s] Using label %s as an annotation: %s", c.Service, key, strings.Join(errs, ", "))
+			delete(c.Labels, key)
+			if _, ok := c.Annotations[key]; !ok {
+				c.Annotations = mergeStrings(c.Annotations, map[string]string{key: value})
+			}
+		}
+	}
+}
+
+func mergeStrings(defaults map[string]string, values map[string]string) map[string]string {
+	if len(defaults) == 0 && len(values) == 0 {
+		return nil
+	}
+	merged := make(map[string]string)
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ManagedBy is the value of the app.kubernetes.io/managed-by label
+const ManagedBy = "smarti"
+
+var invalidLabelChars = regexp.MustCompile("[^A-Za-z0-9._-]+")
+
+// ToLabelValue replaces the characters that are not allowed in label values and truncates it to 63 characters
+func ToLabelValue(value string) string {
+	value = invalidLabelChars.ReplaceAllString(value, "-")
+	if len(value) > 63 {
+		value = value[:63]
+	}
+	return strings.Trim(value, "-_.")
+}
+
+// ToRecommendedLabels returns the app.kubernetes.io labels, part-of is the group the container is defined in
+func (c Container) ToRecommendedLabels() map[string]string {
+	labels := map[string]string{
+		"app.kubernetes.io/name":       ToLabelValue(ToName(c.ImageName)),
+		"app.kubernetes.io/instance":   ToLabelValue(c.Service),
+		"app.kubernetes.io/version":    ToLabelValue(c.ImageTag),
+		"app.kubernetes.io/component":  ToLabelValue(c.Component),
+		"app.kubernetes.io/part-of":    ToLabelValue(c.Group.Name),
+		"app.kubernetes.io/managed-by": ManagedBy,
+	}
+	for k, v := range labels {
+		if v == "" {
+			delete(labels, k)
+		}
+	}
+	return labels
+}
+
+// varStrings returns a map variable such as common_labels with its values converted to strings
+func (c Container) varStrings(name string) map[string]string {
+	values := make(map[string]string)
+	if vars, ok := c.Group.Vars[name].(map[string]interface{}); ok {
+		for k, v := range vars {
+			values[k] = fmt.Sprintf("%v", v)
+		}
+	}
+	return values
+}
+
+// ToLabels returns the recommended labels, the common_labels variable and the container labels, in increasing precedence
+func (c Container) ToLabels() map[string]string {
+	labels := c.ToRecommendedLabels()
+	for k, v := range c.varStrings("common_labels") {
+		labels[k] = ToLabelValue(v)
+	}
+	for k, v := range c.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// ToAnnotations returns the common_annotations variable overridden by the container annotations
+func (c Container) ToAnnotations() map[string]string {
+	return mergeStrings(c.varStrings("common_annotations"), c.Annotations)
+}
+
+// ToObjectMeta returns the metadata for the objects generated for the container
+func (c Container) ToObjectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   c.ToNamespace(),
+		Labels:      c.ToLabels(),
+		Annotations: c.ToAnnotations(),
+	}
+}
+
+// ToPodLabels adds the app label used by selectors
+func (c Container) ToPodLabels() map[string]string {
+	return mergeStrings(c.ToLabels(), map[string]string{"app": c.Service})
+}
+
+// ToHostname returns the first label of hostname, the pod's domain is determined by its service
+func (c Container) ToHostname() string {
+	if strings.Contains(c.Hostname, ".") {
+		log.Warnf("[%s] Using %s as hostname, pods cannot have a fully qualified hostname", c.Service, strings.Split(c.Hostname, ".")[0])
+	}
+	return strings.Split(c.Hostname, ".")[0]
+}
+
+// ToCommandArgs follows docker semantics when an entrypoint is specified: the entrypoint replaces the image
+// entrypoint and the command becomes its arguments
+func (c Container) ToCommandArgs() ([]string, []string) {
+	if len(c.Entrypoint) == 0 {
+		return c.Command, c.Args
+	}
+	var args []string
+	args = append(args, c.Command...)
+	args = append(args, c.Args...)
+	return c.Entrypoint, args
+}
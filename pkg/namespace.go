@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+// ToNamespace returns the namespace the container's objects are created in, a namespace specified
+// with --namespace or as an extra var overrides the namespace of every container
+func (c Container) ToNamespace() string {
+	if namespace, ok := c.Group.Inventory.Vars["namespace"]; ok {
+		return fmt.Sprintf("%v", namespace)
+	}
+	if c.Namespace != "" {
+		return c.Namespace
+	}
+	return c.Group.Get("namespace")
+}
+
+// ToNamespaceObject returns a Namespace with the namespace_labels and namespace_annotations variables,
+// pod_security (privileged, baseline or restricted) adds the Pod Security admission labels
+func (c Container) ToNamespaceObject() v1.Namespace {
+	namespace := v1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Namespace",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        c.ToNamespace(),
+			Labels:      c.varStrings("namespace_labels"),
+			Annotations: mergeStrings(nil, c.varStrings("namespace_annotations")),
+		},
+	}
+	namespace.Labels["app.kubernetes.io/managed-by"] = ManagedBy
+	if level := strings.ToLower(c.Group.Get("pod_security")); level != "" {
+		if level != "privileged" && level != "baseline" && level != "restricted" {
+			log.Warnf("[%s] Unknown pod_security level %s", namespace.Name, level)
+		}
+		for _, mode := range []string{"enforce", "audit", "warn"} {
+			namespace.Labels["pod-security.kubernetes.io/"+mode] = level
+		}
+	}
+	return namespace
+}
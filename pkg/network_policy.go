@@ -0,0 +1,245 @@
+package pkg
+
+import (
+	"k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"net"
+	"sort"
+)
+
+// DefaultIngressNamespace is the namespace of the ingress controller when ingress_namespace is not specified
+const DefaultIngressNamespace = "ingress-nginx"
+
+// NetworkPeer is a service or CIDR that a container depends on or accepts traffic from
+type NetworkPeer struct {
+	Service   string
+	CIDR      string
+	Container *Container
+}
+
+// ResolvePeers resolves depends_on and allow_from entries, which can be a CIDR, a group (all of its services) or a service name
+func (inv Inventory) ResolvePeers(name string) []NetworkPeer {
+	if _, _, err := net.ParseCIDR(name); err == nil {
+		return []NetworkPeer{{CIDR: name}}
+	}
+	if group, ok := inv.Groups[name]; ok && len(group.Containers) > 0 {
+		var peers []NetworkPeer
+		for _, c := range group.Containers {
+			peers = append(peers, NetworkPeer{Service: c.Service, Container: c})
+		}
+		return peers
+	}
+	// services in groups excluded by --limit are still selected by their app label
+	service := ToName(name)
+	for _, c := range inv.Containers() {
+		if c.Service == service {
+			return []NetworkPeer{{Service: service, Container: c}}
+		}
+	}
+	return []NetworkPeer{{Service: service}}
+}
+
+func (c Container) hasPeer(inv Inventory, names []string) bool {
+	for _, name := range names {
+		for _, peer := range inv.ResolvePeers(name) {
+			if peer.Service == c.Service {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IngressPeers are the allow_from entries of the container and the services that depend on it
+func (c Container) IngressPeers() []NetworkPeer {
+	inv := *c.Group.Inventory
+	var peers []NetworkPeer
+	for _, name := range c.AllowFrom {
+		peers = append(peers, inv.ResolvePeers(name)...)
+	}
+	for _, other := range inv.Containers() {
+		if other.Service != c.Service && c.hasPeer(inv, other.DependsOn) {
+			peers = append(peers, NetworkPeer{Service: other.Service, Container: other})
+		}
+	}
+	return uniquePeers(peers)
+}
+
+// EgressPeers are the depends_on entries of the container and the services that allow traffic from it
+func (c Container) EgressPeers() []NetworkPeer {
+	inv := *c.Group.Inventory
+	var peers []NetworkPeer
+	for _, name := range c.DependsOn {
+		peers = append(peers, inv.ResolvePeers(name)...)
+	}
+	for _, other := range inv.Containers() {
+		if other.Service != c.Service && c.hasPeer(inv, other.AllowFrom) {
+			peers = append(peers, NetworkPeer{Service: other.Service, Container: other})
+		}
+	}
+	return uniquePeers(peers)
+}
+
+func uniquePeers(peers []NetworkPeer) []NetworkPeer {
+	var unique []NetworkPeer
+	seen := make(map[string]bool)
+	for _, peer := range peers {
+		key := peer.Service + peer.CIDR
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, peer)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		return unique[i].Service+unique[i].CIDR < unique[j].Service+unique[j].CIDR
+	})
+	return unique
+}
+
+// ToNetworkPolicyPeer selects the pods of the service, adding a namespace selector for services in other namespaces
+func (peer NetworkPeer) ToNetworkPolicyPeer(namespace string) networkingv1.NetworkPolicyPeer {
+	if peer.CIDR != "" {
+		return networkingv1.NetworkPolicyPeer{
+			IPBlock: &networkingv1.IPBlock{CIDR: peer.CIDR},
+		}
+	}
+	pods := map[string]string{"app": peer.Service}
+	if peer.Container != nil && peer.Container.ToNamespace() != namespace {
+		return ToNamespacePeer(peer.Container.ToNamespace(), pods)
+	}
+	return networkingv1.NetworkPolicyPeer{
+		PodSelector: &metav1.LabelSelector{MatchLabels: pods},
+	}
+}
+
+// ToNetworkPolicyPorts returns the target ports of the pod, or nil (all ports) for unknown services
+func (peer NetworkPeer) ToNetworkPolicyPorts() []networkingv1.NetworkPolicyPort {
+	if peer.Container == nil {
+		return nil
+	}
+	return peer.Container.ToNetworkPolicyPorts()
+}
+
+func (c Container) ToNetworkPolicyPorts() []networkingv1.NetworkPolicyPort {
+	var ports []networkingv1.NetworkPolicyPort
+	for _, s := range append([]*Container{&c}, c.Sidecars...) {
+		for _, port := range s.Ports {
+			protocol := v1.ProtocolTCP
+			if port.Protocol == "udp" {
+				protocol = v1.ProtocolUDP
+			}
+			target := intstr.FromInt(port.Target)
+			ports = append(ports, networkingv1.NetworkPolicyPort{
+				Protocol: &protocol,
+				Port:     &target,
+			})
+		}
+	}
+	return ports
+}
+
+func ToNamespacePeer(namespace string, pods map[string]string) networkingv1.NetworkPolicyPeer {
+	peer := networkingv1.NetworkPolicyPeer{
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"kubernetes.io/metadata.name": namespace},
+		},
+	}
+	if pods != nil {
+		peer.PodSelector = &metav1.LabelSelector{MatchLabels: pods}
+	}
+	return peer
+}
+
+// ToDNSEgressRule allows lookups against the cluster DNS
+func ToDNSEgressRule() networkingv1.NetworkPolicyEgressRule {
+	udp := v1.ProtocolUDP
+	tcp := v1.ProtocolTCP
+	port := intstr.FromInt(53)
+	return networkingv1.NetworkPolicyEgressRule{
+		To: []networkingv1.NetworkPolicyPeer{
+			ToNamespacePeer("kube-system", map[string]string{"k8s-app": "kube-dns"}),
+		},
+		Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: &udp, Port: &port},
+			{Protocol: &tcp, Port: &port},
+		},
+	}
+}
+
+// IsNetworkPolicyEnabled returns true when network_policy is set for the container's groups
+func (c Container) IsNetworkPolicyEnabled() bool {
+	return c.Group.Get("network_policy") == "true"
+}
+
+// ToIngressControllerPeer selects the namespace of the gateway in gateway mode, and the ingress_namespace otherwise
+func (c Container) ToIngressControllerPeer() networkingv1.NetworkPolicyPeer {
+	if c.IsGatewayMode() {
+		if namespace := c.ToParentReference().Namespace; namespace != nil {
+			return ToNamespacePeer(*namespace, nil)
+		}
+		// a gateway without a namespace is in the namespace of the route
+		return networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}}
+	}
+	namespace := c.Group.Get("ingress_namespace")
+	if namespace == "" {
+		namespace = DefaultIngressNamespace
+	}
+	return ToNamespacePeer(namespace, nil)
+}
+
+func (c Container) ToNetworkPolicy() networkingv1.NetworkPolicy {
+	policy := networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "networking.k8s.io/v1",
+			Kind:       "NetworkPolicy",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *c.ToSelector(),
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+			Ingress:     []networkingv1.NetworkPolicyIngressRule{},
+			Egress:      []networkingv1.NetworkPolicyEgressRule{ToDNSEgressRule()},
+		},
+	}
+
+	ports := c.ToNetworkPolicyPorts()
+	if len(ports) > 0 {
+		// replicas of the service talk to each other, e.g. to form a cluster
+		self := []networkingv1.NetworkPolicyPeer{{PodSelector: c.ToSelector()}}
+		policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			From:  self,
+			Ports: ports,
+		})
+		policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+			To:    self,
+			Ports: ports,
+		})
+	}
+
+	var from []networkingv1.NetworkPolicyPeer
+	for _, peer := range c.IngressPeers() {
+		from = append(from, peer.ToNetworkPolicyPeer(c.ToNamespace()))
+	}
+	if len(from) > 0 {
+		policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			From:  from,
+			Ports: ports,
+		})
+	}
+	if len(c.ToIngressRoutes()) > 0 {
+		policy.Spec.Ingress = append(policy.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			From:  []networkingv1.NetworkPolicyPeer{c.ToIngressControllerPeer()},
+			Ports: ports,
+		})
+	}
+
+	for _, peer := range c.EgressPeers() {
+		policy.Spec.Egress = append(policy.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
+			To:    []networkingv1.NetworkPolicyPeer{peer.ToNetworkPolicyPeer(c.ToNamespace())},
+			Ports: peer.ToNetworkPolicyPorts(),
+		})
+	}
+	return policy
+}
@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+const networkInventory = `
+containers:
+  - image: web:1
+    ports: ["8080"]
+    depends_on: [api, 10.0.0.0/8]
+  - image: api:1
+    ports: ["9090"]
+    allow_from: [cron]
+    depends_on: [db, billing]
+  - image: cron:1
+`
+
+const dbInventory = `
+namespace: data
+containers:
+  - image: postgres:12
+    service: postgres
+    ports: ["5432"]
+`
+
+func peerNames(peers []NetworkPeer) []string {
+	var names []string
+	for _, peer := range peers {
+		name := peer.Service + peer.CIDR
+		if peer.Container == nil {
+			name += "?"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestResolvePeers(t *testing.T) {
+	inv := testInventory(t, map[string]string{"all": networkInventory, "db": dbInventory})
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"10.0.0.0/8", []string{"10.0.0.0/8?"}},
+		{"db", []string{"postgres"}},
+		{"api", []string{"api"}},
+		{"billing", []string{"billing?"}},
+	}
+	for _, test := range tests {
+		if peers := peerNames(inv.ResolvePeers(test.name)); !reflect.DeepEqual(peers, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, peers)
+		}
+	}
+}
+
+func TestNetworkPeers(t *testing.T) {
+	inv := testInventory(t, map[string]string{"all": networkInventory, "db": dbInventory})
+	tests := []struct {
+		service string
+		ingress []string
+		egress  []string
+	}{
+		{"web", nil, []string{"10.0.0.0/8?", "api"}},
+		{"api", []string{"cron", "web"}, []string{"billing?", "postgres"}},
+		{"cron", nil, []string{"api"}},
+		{"postgres", []string{"api"}, nil},
+	}
+	for _, test := range tests {
+		c := testContainer(t, inv, test.service)
+		if peers := peerNames(c.IngressPeers()); !reflect.DeepEqual(peers, test.ingress) {
+			t.Errorf("%s: expected ingress from %v, got %v", test.service, test.ingress, peers)
+		}
+		if peers := peerNames(c.EgressPeers()); !reflect.DeepEqual(peers, test.egress) {
+			t.Errorf("%s: expected egress to %v, got %v", test.service, test.egress, peers)
+		}
+	}
+}
+
+func TestToNetworkPolicyPeer(t *testing.T) {
+	inv := testInventory(t, map[string]string{"all": networkInventory, "db": dbInventory})
+	api := testContainer(t, inv, "api")
+	postgres := testContainer(t, inv, "postgres")
+	tests := []struct {
+		name      string
+		peer      NetworkPeer
+		namespace string
+		pods      map[string]string
+		cidr      string
+	}{
+		{"cidr", NetworkPeer{CIDR: "10.0.0.0/8"}, "", nil, "10.0.0.0/8"},
+		{"same namespace", NetworkPeer{Service: "api", Container: api}, "", map[string]string{"app": "api"}, ""},
+		{"other namespace", NetworkPeer{Service: "postgres", Container: postgres}, "data", map[string]string{"app": "postgres"}, ""},
+		{"unknown service", NetworkPeer{Service: "billing"}, "", map[string]string{"app": "billing"}, ""},
+	}
+	for _, test := range tests {
+		peer := test.peer.ToNetworkPolicyPeer(api.ToNamespace())
+		namespace := ""
+		if peer.NamespaceSelector != nil {
+			namespace = peer.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"]
+		}
+		var pods map[string]string
+		if peer.PodSelector != nil {
+			pods = peer.PodSelector.MatchLabels
+		}
+		cidr := ""
+		if peer.IPBlock != nil {
+			cidr = peer.IPBlock.CIDR
+		}
+		if namespace != test.namespace || !reflect.DeepEqual(pods, test.pods) || cidr != test.cidr {
+			t.Errorf("%s: expected %q %v %q, got %q %v %q", test.name, test.namespace, test.pods, test.cidr, namespace, pods, cidr)
+		}
+	}
+}
+
+func TestToIngressControllerPeer(t *testing.T) {
+	tests := []struct {
+		name      string
+		vars      string
+		namespace string
+		pods      bool
+	}{
+		{"default controller", "", DefaultIngressNamespace, false},
+		{"ingress_namespace", "ingress_namespace: traefik", "traefik", false},
+		{"gateway namespace", "ingress_mode: gateway\ngateway: infra/public:https", "infra", false},
+		{"gateway in the route namespace", "ingress_mode: gateway\ngateway: public", "", true},
+	}
+	for _, test := range tests {
+		inv := testInventory(t, map[string]string{"all": test.vars + "\ncontainers:\n  - image: web:1\n    ports: [\"8080\"]\n    ingress: web.example.com\n"})
+		peer := testContainer(t, inv, "web").ToIngressControllerPeer()
+		namespace := ""
+		if peer.NamespaceSelector != nil {
+			namespace = peer.NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"]
+		}
+		if namespace != test.namespace || (peer.PodSelector != nil) != test.pods {
+			t.Errorf("%s: expected namespace %q, got %q", test.name, test.namespace, namespace)
+		}
+	}
+}
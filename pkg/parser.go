@@ -6,9 +6,7 @@ import (
 	"github.com/spf13/cobra"
 	"path"
 	"strings"
-	"github.com/knq/ini"
 	"regexp"
-	"github.com/getlantern/deepcopy"
 	log "github.com/sirupsen/logrus"
 	"sync"
 )
@@ -16,15 +14,32 @@ import (
 func Parse(cmd *cobra.Command) Inventory {
 	dir := cmd.Flag("inventory").Value.String()
 	inventory := NewInventory()
-	inventory.Limit = cmd.Flag("limit").Value.String()
+	config := EffectiveConfig(cmd)
+	inventory.Limit = config.Limit
+	inventory.HashBehaviour = config.HashBehaviour
+	if file := cmd.Flag("vault-password-file").Value.String(); file != "" {
+		password, err := ReadVaultPassword(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		inventory.VaultPassword = password
+	}
 
 	inventory.Vars["inventory_name"] = path.Base(dir)
 	inventory.Vars["inventory_dir"] = dir
 	inventory.Vars["inventory_file"] = dir + "/hosts"
-	extra, _ := cmd.Flags().GetStringSlice("extra-vars")
-	ParseExtraVars(extra, inventory)
+	ParseExtraVars(config.ExtraVars, inventory)
+	ParseFlagVars(cmd, inventory)
 
 	ParseInventory(dir, inventory)
+
+	// smarti.yml settings are defaults that any inventory variable overrides
+	all := inventory.Groups["all"]
+	for key, value := range config.Vars() {
+		if _, ok := all.Vars[key]; !ok {
+			all.Vars[key] = value
+		}
+	}
 	ParseContainers(inventory)
 	//var hosts = make(map[string]*Host)
 
@@ -63,7 +78,9 @@ func ParseContainers(inv Inventory) {
 		if containers != nil {
 			for _, container := range containers.([]interface{}) {
 				c := new(Container)
-				deepcopy.Copy(c, container)
+				if err := deepCopy(c, container); err != nil {
+					log.Errorf("Invalid container in %s: %v", group.Name, err)
+				}
 				c.Group = *group
 				group.Containers = append(group.Containers, c)
 			}
@@ -83,7 +100,7 @@ func ParseContainers(inv Inventory) {
 
 func ParseInventory(dir string, inventory Inventory) {
 	if _, err := os.Stat(dir); err != nil {
-		log.Debugf("Parsing inventory from string: " + dir)
+		log.Debugf("Parsing inventory from string: %s", dir)
 
 		for _, host := range strings.Split(dir, ",") {
 			inventory.AddHost(Host{
@@ -92,7 +109,8 @@ func ParseInventory(dir string, inventory Inventory) {
 
 		}
 	} else {
-		log.Debugf("Parsing inventory from file: " + dir)
+		log.Debugf("Parsing inventory from file: %s", dir)
+		LoadPlugins(dir)
 		ParseGroups(dir+"/group_vars", inventory)
 		ParseGroupIni(dir, inventory)
 	}
@@ -102,11 +120,25 @@ func ParseInventory(dir string, inventory Inventory) {
 			Name: "all",
 		})
 	}
-	log.Debugf("Groups: %s", inventory.Groups)
-	log.Debugf("Hosts: %s", inventory.Hosts)
+	log.Debugf("Groups: %v", inventory.Groups)
+	log.Debugf("Hosts: %v", inventory.Hosts)
 
 }
 
+// flagVars maps flags to the inventory variables they override when specified
+var flagVars = map[string]string{
+	"kube-version": "kube_version",
+	"namespace":    "namespace",
+}
+
+func ParseFlagVars(cmd *cobra.Command, inventory Inventory) {
+	for name, key := range flagVars {
+		if flag := cmd.Flag(name); flag != nil && flag.Changed {
+			inventory.Vars[key] = flag.Value.String()
+		}
+	}
+}
+
 func ParseExtraVars(extra []string, inventory Inventory) {
 	for _, val := range extra {
 		if strings.HasPrefix(val, "@") {
@@ -129,14 +161,14 @@ func ParseGroupIni(dir string, inventory Inventory) {
 	// ini syntax doesn't support just a key value so we replace all names with name=name
 	re := regexp.MustCompile("(?m)(^\\w+)(.*)")
 	content = re.ReplaceAllString(content, "$1=$1 $2")
-	cfg, err := ini.LoadString(content)
+	cfg, err := LoadIni(content)
 	if err != nil {
 		log.Fatalf("%s\n%s", err, content)
 	}
 
-	for _, section := range cfg.SectionNames() {
+	for _, section := range cfg.SectionStrings() {
 		if strings.HasSuffix(section, ":children") {
-			for _, key := range cfg.GetSection(section).Keys() {
+			for _, key := range cfg.Section(section).KeyStrings() {
 				//FIXME what happens if the group has not been declared yet?
 				//FIXME what happens if the group has not been declared yet?
 				group := inventory.Groups[key]
@@ -170,9 +202,7 @@ func ParseGroups(dir string, inventory Inventory) {
 			for _, c := range children {
 				vars := ParseFile(dir+"/"+f.Name()+"/"+c.Name(), inventory)
 				if group.Vars != nil {
-					for k, v := range vars {
-						group.Vars[k] = v
-					}
+					inventory.PutAll(vars, group.Vars)
 				} else {
 					group.Vars = vars
 				}
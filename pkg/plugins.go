@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PluginRequest is written to the stdin of a plugin executable
+type PluginRequest struct {
+	Name  string        `json:"name"`
+	Input interface{}   `json:"input,omitempty"`
+	Param interface{}   `json:"param,omitempty"`
+	Terms []interface{} `json:"terms,omitempty"`
+}
+
+// PluginResponse is read from the stdout of a plugin executable
+type PluginResponse struct {
+	Result interface{} `json:"result"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// windowsPluginExtensions are treated as executable on windows, which has no executable bit
+var windowsPluginExtensions = []string{".exe", ".bat", ".cmd", ".ps1"}
+
+// LoadPlugins registers every executable in the filter_plugins and lookup_plugins directories
+// of an inventory, using the file name without extension as the filter or lookup name
+func LoadPlugins(dir string) {
+	for _, plugin := range findPlugins(filepath.Join(dir, "filter_plugins")) {
+		plugin := plugin
+		log.Infof("Registering filter plugin %s", plugin)
+		RegisterFilter(pluginName(plugin), func(in interface{}, param interface{}) (interface{}, error) {
+			return execPlugin(plugin, PluginRequest{Name: pluginName(plugin), Input: in, Param: param})
+		})
+	}
+	for _, plugin := range findPlugins(filepath.Join(dir, "lookup_plugins")) {
+		plugin := plugin
+		log.Infof("Registering lookup plugin %s", plugin)
+		RegisterLookup(pluginName(plugin), func(terms ...interface{}) (interface{}, error) {
+			return execPlugin(plugin, PluginRequest{Name: pluginName(plugin), Terms: terms})
+		})
+	}
+}
+
+func findPlugins(dir string) []string {
+	var plugins []string
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return plugins
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if !isExecutable(runtime.GOOS, f.Name(), f.Mode()) {
+			log.Warnf("Skipping non executable plugin %s", filepath.Join(dir, f.Name()))
+			continue
+		}
+		plugins = append(plugins, filepath.Join(dir, f.Name()))
+	}
+	return plugins
+}
+
+func isExecutable(goos string, name string, mode os.FileMode) bool {
+	if goos != "windows" {
+		return mode&0111 != 0
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range windowsPluginExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func pluginName(plugin string) string {
+	name := filepath.Base(plugin)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// pluginCommand runs powershell scripts through powershell, other plugins are executed directly
+func pluginCommand(plugin string) *exec.Cmd {
+	if strings.ToLower(filepath.Ext(plugin)) == ".ps1" {
+		return exec.Command("powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-File", plugin)
+	}
+	return exec.Command(plugin)
+}
+
+func execPlugin(plugin string, request PluginRequest) (interface{}, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := pluginCommand(plugin)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %v: %s", plugin, err, stderr.String())
+	}
+
+	var response PluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("%s returned invalid json: %v", plugin, err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s: %s", plugin, response.Error)
+	}
+	return response.Result, nil
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestIsExecutable(t *testing.T) {
+	tests := []struct {
+		goos     string
+		name     string
+		mode     os.FileMode
+		expected bool
+	}{
+		{"linux", "upper", 0755, true},
+		{"linux", "upper.py", 0744, true},
+		{"linux", "upper.py", 0644, false},
+		{"linux", "upper.exe", 0644, false},
+		{"windows", "upper.exe", 0644, true},
+		{"windows", "upper.BAT", 0644, true},
+		{"windows", "upper.cmd", 0644, true},
+		{"windows", "upper.ps1", 0644, true},
+		{"windows", "upper.py", 0755, false},
+		{"windows", "upper", 0755, false},
+	}
+	for _, test := range tests {
+		if executable := isExecutable(test.goos, test.name, test.mode); executable != test.expected {
+			t.Errorf("%s %s %v: expected %v, got %v", test.goos, test.name, test.mode, test.expected, executable)
+		}
+	}
+}
+
+// writePlugin writes a shell script plugin, plugins are tested with sh so they are skipped on windows
+func writePlugin(t *testing.T, dir string, name string, script string, mode os.FileMode) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins are not executable on windows")
+	}
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte("#!/bin/sh\n"+script+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestFindPlugins(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "upper.sh", "cat", 0755)
+	writePlugin(t, dir, "notes.txt", "cat", 0644)
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	plugins := findPlugins(dir)
+	if len(plugins) != 1 || pluginName(plugins[0]) != "upper" {
+		t.Errorf("expected only the upper plugin, got %v", plugins)
+	}
+	if plugins := findPlugins(filepath.Join(dir, "missing")); len(plugins) != 0 {
+		t.Errorf("expected no plugins in a missing directory, got %v", plugins)
+	}
+}
+
+func TestExecPlugin(t *testing.T) {
+	dir := t.TempDir()
+	// echoes the request back as the result
+	echo := writePlugin(t, dir, "echo", `printf '{"result": %s}' "$(cat)"`, 0755)
+	result, err := execPlugin(echo, PluginRequest{Name: "echo", Input: "web", Param: 2, Terms: []interface{}{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, ok := result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected the request as the result, got %v", result)
+	}
+	expected := map[string]interface{}{"name": "echo", "input": "web", "param": float64(2)}
+	for key, value := range expected {
+		if request[key] != value {
+			t.Errorf("expected %s=%v in the request, got %v", key, value, request[key])
+		}
+	}
+	if terms, ok := request["terms"].([]interface{}); !ok || len(terms) != 1 || terms[0] != "a" {
+		t.Errorf("expected the terms [a] in the request, got %v", request["terms"])
+	}
+
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"error response", `cat >/dev/null; echo '{"error": "boom"}'`},
+		{"invalid json", `cat >/dev/null; echo 'boom'`},
+		{"non zero exit", `cat >/dev/null; echo '{"result": 1}'; exit 1`},
+	}
+	for i, test := range tests {
+		plugin := writePlugin(t, dir, fmt.Sprintf("fail%d", i), test.script, 0755)
+		if result, err := execPlugin(plugin, PluginRequest{Name: "fail"}); err == nil {
+			t.Errorf("%s: expected an error, got %v", test.name, result)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mattn/go-shellwords"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"strconv"
+	"strings"
+)
+
+// HealthCheck uses the first of grpc, url, cmd and port that is set, port is a number or the name of a container port
+type HealthCheck struct {
+	Cmd              string
+	Url              string
+	Port             StringOrInt
+	Scheme           string            `json:"scheme,omitempty"`
+	Host             string            `json:"host,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Grpc             bool              `json:"grpc,omitempty"`
+	GrpcService      string            `json:"grpc_service,omitempty"`
+	Period           int32             `json:"period,omitempty"`
+	Timeout          int32             `json:"timeout,omitempty"`
+	Delay            int32             `json:"delay,omitempty"`
+	SuccessThreshold int32             `json:"success_threshold,omitempty"`
+	FailureThreshold int32             `json:"failure_threshold,omitempty"`
+}
+
+// applyProbeDefaults adds a readiness probe on the first port and copies it to the liveness probe, a startup probe
+// without a check reuses the readiness check
+func (c *Container) applyProbeDefaults(defaults ContainerDefaults) {
+	if c.initContainer {
+		// init containers get no default probes, explicit ones are rejected
+		c.ReadinessProbe = c.validateProbe("readiness", c.ReadinessProbe)
+		c.LivenessProbe = c.validateProbe("liveness", c.LivenessProbe)
+		c.StartupProbe = c.validateProbe("startup", c.StartupProbe)
+		return
+	}
+	if len(c.Ports) > 0 && c.ReadinessProbe == nil {
+		c.ReadinessProbe = &HealthCheck{
+			Port: c.Ports[0].Target,
+		}
+
+		if defaults.ReadinessProbe != nil {
+			c.ReadinessProbe.Delay = defaults.ReadinessProbe.Delay
+			c.ReadinessProbe.Timeout = defaults.ReadinessProbe.Timeout
+			c.ReadinessProbe.Period = defaults.ReadinessProbe.Period
+			c.ReadinessProbe.SuccessThreshold = defaults.ReadinessProbe.SuccessThreshold
+			c.ReadinessProbe.FailureThreshold = defaults.ReadinessProbe.FailureThreshold
+		}
+	}
+
+	if c.ReadinessProbe != nil && c.LivenessProbe == nil {
+		// liveness probes must succeed once, readiness probes can require more
+		liveness := *c.ReadinessProbe
+		liveness.SuccessThreshold = 0
+		c.LivenessProbe = &liveness
+	}
+	if c.LivenessProbe != nil && defaults.LivenessProbe != nil {
+		if c.LivenessProbe.Timeout == 0 {
+			c.LivenessProbe.Timeout = defaults.LivenessProbe.Timeout
+		}
+		if c.LivenessProbe.Period == 0 {
+			c.LivenessProbe.Period = defaults.LivenessProbe.Period
+		}
+		if c.LivenessProbe.FailureThreshold == 0 {
+			c.LivenessProbe.FailureThreshold = defaults.LivenessProbe.FailureThreshold
+		}
+	} else if defaults.LivenessProbe != nil {
+		liveness := *defaults.LivenessProbe
+		c.LivenessProbe = &liveness
+	}
+
+	if c.StartupProbe == nil && defaults.StartupProbe != nil {
+		startup := *defaults.StartupProbe
+		c.StartupProbe = &startup
+	}
+	if c.StartupProbe != nil && !c.StartupProbe.hasCheck() && c.ReadinessProbe != nil {
+		c.StartupProbe.Cmd = c.ReadinessProbe.Cmd
+		c.StartupProbe.Url = c.ReadinessProbe.Url
+		c.StartupProbe.Port = c.ReadinessProbe.Port
+		c.StartupProbe.Scheme = c.ReadinessProbe.Scheme
+		c.StartupProbe.Host = c.ReadinessProbe.Host
+		c.StartupProbe.Headers = c.ReadinessProbe.Headers
+		c.StartupProbe.Grpc = c.ReadinessProbe.Grpc
+		c.StartupProbe.GrpcService = c.ReadinessProbe.GrpcService
+	}
+
+	c.ReadinessProbe = c.validateProbe("readiness", c.ReadinessProbe)
+	c.LivenessProbe = c.validateProbe("liveness", c.LivenessProbe)
+	c.StartupProbe = c.validateProbe("startup", c.StartupProbe)
+}
+
+// validateProbe resolves the port of the probe and drops it with a warning if it cannot produce a handler for
+// the kube_version or the container is an init container
+func (c Container) validateProbe(kind string, probe *HealthCheck) *HealthCheck {
+	if probe == nil {
+		return nil
+	}
+	if c.initContainer {
+		log.Warnf("[%s] Ignoring %s probe: init containers cannot have probes", c.Service, kind)
+		return nil
+	}
+	resolved := *probe
+	if resolved.Port == nil && (resolved.Url != "" || resolved.IsGrpc()) && len(c.Ports) > 0 {
+		resolved.Port = c.Ports[0].Target
+	}
+	port := resolved.ToPort()
+	if port.Type == intstr.String {
+		target, err := c.ResolvePort(port.StrVal)
+		if err != nil {
+			log.Warnf("[%s] Ignoring %s probe: %v", c.Service, kind, err)
+			return nil
+		}
+		resolved.Port = target
+	}
+	if kind != "readiness" && resolved.SuccessThreshold > 1 {
+		log.Warnf("[%s] The success_threshold of %s probes must be 1", c.Service, kind)
+		resolved.SuccessThreshold = 1
+	}
+	if err := resolved.Validate(); err != nil {
+		log.Warnf("[%s] Ignoring %s probe: %v", c.Service, kind, err)
+		return nil
+	}
+	if version := c.KubeVersion(); resolved.IsGrpc() && !version.AtLeast(24) {
+		log.Warnf("[%s] Ignoring %s probe: grpc checks require kube_version 1.24 or later, got %s", c.Service, kind, version)
+		return nil
+	}
+	return &resolved
+}
+
+// ResolvePort returns the target of the port with the given name
+func (c Container) ResolvePort(name string) (int, error) {
+	for _, port := range c.Ports {
+		if port.Name == name {
+			return port.Target, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown port %s", name)
+}
+
+func (h HealthCheck) IsGrpc() bool {
+	return h.Grpc || h.GrpcService != ""
+}
+
+func (h HealthCheck) hasCheck() bool {
+	return h.IsGrpc() || h.Url != "" || strings.TrimSpace(h.Cmd) != "" || h.Port != nil
+}
+
+// ToPort returns the port as a number when it is numeric and as a name otherwise
+func (h HealthCheck) ToPort() intstr.IntOrString {
+	switch port := h.Port.(type) {
+	case int:
+		return intstr.FromInt(port)
+	case float64:
+		return intstr.FromInt(int(port))
+	case string:
+		if n, err := strconv.Atoi(port); err == nil {
+			return intstr.FromInt(n)
+		}
+		return intstr.FromString(port)
+	}
+	return intstr.IntOrString{}
+}
+
+// ToCommand splits cmd like a shell would, without running a shell
+func (h HealthCheck) ToCommand() ([]string, error) {
+	return shellwords.Parse(h.Cmd)
+}
+
+func (h HealthCheck) ToScheme() v1.URIScheme {
+	if h.Scheme == "" {
+		return v1.URISchemeHTTP
+	}
+	return v1.URIScheme(strings.ToUpper(h.Scheme))
+}
+
+func (h HealthCheck) ToHTTPHeaders() []v1.HTTPHeader {
+	var headers []v1.HTTPHeader
+	for _, name := range sortedKeys(h.Headers) {
+		headers = append(headers, v1.HTTPHeader{Name: name, Value: h.Headers[name]})
+	}
+	return headers
+}
+
+// Validate returns an error if the probe has no check or the check cannot be run by the kubelet
+func (h HealthCheck) Validate() error {
+	port := h.ToPort()
+	hasPort := port.IntVal > 0 || port.StrVal != ""
+	switch {
+	case h.IsGrpc():
+		if port.Type != intstr.Int || port.IntVal <= 0 {
+			return errors.New("grpc checks require a numeric port")
+		}
+	case h.Url != "":
+		if !hasPort {
+			return fmt.Errorf("missing port for %s", h.Url)
+		}
+		if scheme := h.ToScheme(); scheme != v1.URISchemeHTTP && scheme != v1.URISchemeHTTPS {
+			return fmt.Errorf("invalid scheme %s, use http or https", h.Scheme)
+		}
+	case strings.TrimSpace(h.Cmd) != "":
+		cmd, err := h.ToCommand()
+		if err != nil {
+			return fmt.Errorf("invalid cmd %s: %v", h.Cmd, err)
+		}
+		if len(cmd) == 0 {
+			return fmt.Errorf("empty cmd %s", h.Cmd)
+		}
+	case h.Port != nil:
+		if !hasPort {
+			return fmt.Errorf("invalid port %v", h.Port)
+		}
+	default:
+		return errors.New("one of cmd, url, port or grpc is required")
+	}
+	if h.Period < 0 || h.Timeout < 0 || h.Delay < 0 || h.SuccessThreshold < 0 || h.FailureThreshold < 0 {
+		return errors.New("period, timeout, delay and thresholds cannot be negative")
+	}
+	return nil
+}
+
+func (h HealthCheck) ToProbe() *v1.Probe {
+
+	probe := &v1.Probe{
+		TimeoutSeconds:      h.Timeout,
+		PeriodSeconds:       h.Period,
+		InitialDelaySeconds: h.Delay,
+		SuccessThreshold:    h.SuccessThreshold,
+		FailureThreshold:    h.FailureThreshold,
+	}
+
+	port := h.ToPort()
+	if h.IsGrpc() {
+		grpc := &v1.GRPCAction{Port: port.IntVal}
+		if h.GrpcService != "" {
+			service := h.GrpcService
+			grpc.Service = &service
+		}
+		probe.ProbeHandler = v1.ProbeHandler{
+			GRPC: grpc,
+		}
+	} else if h.Url != "" {
+		probe.ProbeHandler = v1.ProbeHandler{
+			HTTPGet: &v1.HTTPGetAction{
+				Path:        h.Url,
+				Port:        port,
+				Host:        h.Host,
+				Scheme:      h.ToScheme(),
+				HTTPHeaders: h.ToHTTPHeaders(),
+			},
+		}
+	} else if strings.TrimSpace(h.Cmd) != "" {
+		cmd, err := h.ToCommand()
+		if err != nil {
+			log.Warnf("Invalid probe cmd %s: %v", h.Cmd, err)
+			return nil
+		}
+		probe.ProbeHandler = v1.ProbeHandler{
+			Exec: &v1.ExecAction{
+				Command: cmd,
+			},
+		}
+	} else if h.Port != nil {
+		probe.ProbeHandler = v1.ProbeHandler{
+			TCPSocket: &v1.TCPSocketAction{
+				Port: port,
+				Host: h.Host,
+			},
+		}
+	} else {
+		return nil
+	}
+
+	return probe
+}
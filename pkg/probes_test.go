@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func probeContainer(kubeVersion string) Container {
+	return Container{
+		Service: "api",
+		Ports:   []ContainerPort{{Published: 80, Target: 8080, Name: "http"}, {Published: 9090, Target: 9091, Name: "grpc"}},
+		Group: Group{
+			Name:      "all",
+			Vars:      map[string]interface{}{"kube_version": kubeVersion},
+			Inventory: &Inventory{Vars: map[string]interface{}{}},
+		},
+	}
+}
+
+func TestResolvePort(t *testing.T) {
+	c := probeContainer("1.24")
+	tests := []struct {
+		name     string
+		expected int
+		err      bool
+	}{
+		{"http", 8080, false},
+		{"grpc", 9091, false},
+		{"metrics", 0, true},
+	}
+	for _, test := range tests {
+		port, err := c.ResolvePort(test.name)
+		if port != test.expected || (err != nil) != test.err {
+			t.Errorf("%s: expected %d (error %v), got %d (%v)", test.name, test.expected, test.err, port, err)
+		}
+	}
+}
+
+func TestValidateProbe(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		kubeVersion string
+		init        bool
+		probe       *HealthCheck
+		expected    *HealthCheck
+	}{
+		{"no probe", "readiness", "1.24", false, nil, nil},
+		{"numeric port", "readiness", "1.24", false, &HealthCheck{Port: "8080"}, &HealthCheck{Port: "8080"}},
+		{"named port", "readiness", "1.24", false, &HealthCheck{Port: "http"}, &HealthCheck{Port: 8080}},
+		{"unknown port", "readiness", "1.24", false, &HealthCheck{Port: "metrics"}, nil},
+		{"url on the first port", "liveness", "1.24", false, &HealthCheck{Url: "/health"}, &HealthCheck{Url: "/health", Port: 8080}},
+		{"grpc on a named port", "readiness", "1.24", false, &HealthCheck{Grpc: true, Port: "grpc"}, &HealthCheck{Grpc: true, Port: 9091}},
+		{"grpc before 1.24", "readiness", "1.23", false, &HealthCheck{Grpc: true, Port: "grpc"}, nil},
+		{"readiness success threshold", "readiness", "1.24", false, &HealthCheck{Port: 8080, SuccessThreshold: 3}, &HealthCheck{Port: 8080, SuccessThreshold: 3}},
+		{"liveness success threshold", "liveness", "1.24", false, &HealthCheck{Port: 8080, SuccessThreshold: 3}, &HealthCheck{Port: 8080, SuccessThreshold: 1}},
+		{"init container", "readiness", "1.24", true, &HealthCheck{Port: 8080}, nil},
+	}
+	for _, test := range tests {
+		c := probeContainer(test.kubeVersion)
+		c.initContainer = test.init
+		if probe := c.validateProbe(test.kind, test.probe); !reflect.DeepEqual(probe, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, probe)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RbacRule grants verbs on resources, resources can include their api group (deployments.apps) and
+// subresource (pods/log), cluster rules are granted with a ClusterRole
+type RbacRule struct {
+	Verbs         []string `json:"verbs,omitempty"`
+	Resources     []string `json:"resources,omitempty"`
+	APIGroups     []string `json:"api_groups,omitempty"`
+	ResourceNames []string `json:"resource_names,omitempty"`
+	Cluster       bool     `json:"cluster,omitempty"`
+}
+
+// UnmarshalJSON also accepts the compact "get,list,watch pods,deployments.apps" form
+func (rule *RbacRule) UnmarshalJSON(b []byte) error {
+	if strings.HasPrefix(string(b), "{") {
+		type rbacRule RbacRule
+		return json.Unmarshal(b, (*rbacRule)(rule))
+	}
+	str, err := strconv.Unquote(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid rbac rule %s: %v", b, err)
+	}
+	fields := strings.Fields(str)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid rbac rule %q, expected \"<verbs> <resources>\"", str)
+	}
+	rule.Verbs = strings.Split(fields[0], ",")
+	rule.Resources = strings.Split(fields[1], ",")
+	return nil
+}
+
+// ToPolicyRules splits the resources by api group unless api_groups are specified
+func (rule RbacRule) ToPolicyRules() []rbacv1.PolicyRule {
+	if len(rule.APIGroups) > 0 {
+		return []rbacv1.PolicyRule{{
+			Verbs:         rule.Verbs,
+			APIGroups:     rule.APIGroups,
+			Resources:     rule.Resources,
+			ResourceNames: rule.ResourceNames,
+		}}
+	}
+	groups := make(map[string][]string)
+	for _, resource := range rule.Resources {
+		name, subresource := resource, ""
+		if i := strings.Index(resource, "/"); i > 0 {
+			name, subresource = resource[:i], resource[i:]
+		}
+		group := ""
+		if i := strings.Index(name, "."); i > 0 {
+			name, group = name[:i], name[i+1:]
+		}
+		groups[group] = append(groups[group], name+subresource)
+	}
+	var names []string
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+
+	var rules []rbacv1.PolicyRule
+	for _, group := range names {
+		rules = append(rules, rbacv1.PolicyRule{
+			Verbs:         rule.Verbs,
+			APIGroups:     []string{group},
+			Resources:     groups[group],
+			ResourceNames: rule.ResourceNames,
+		})
+	}
+	return rules
+}
+
+// IsServiceAccountGenerated is true when create_service_account is set, or when there are rbac rules and
+// create_service_account is not false, an existing service_account is only bound to the rules
+func (c Container) IsServiceAccountGenerated() bool {
+	if c.CreateAccount != nil {
+		return *c.CreateAccount
+	}
+	return len(c.Rbac) > 0 && c.ServiceAccount == ""
+}
+
+// ServiceAccountName returns the referenced service account, or the service name for generated accounts
+func (c Container) ServiceAccountName() string {
+	if c.ServiceAccount != "" {
+		return c.ServiceAccount
+	}
+	if c.IsServiceAccountGenerated() {
+		return c.Service
+	}
+	return ""
+}
+
+// ClusterRoleName prefixes the namespace, as cluster roles are not namespaced
+func (c Container) ClusterRoleName() string {
+	if c.ToNamespace() == "" {
+		return c.Service
+	}
+	return c.ToNamespace() + "-" + c.Service
+}
+
+func (c Container) ToServiceAccount() v1.ServiceAccount {
+	return v1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ServiceAccount",
+		},
+		ObjectMeta: c.ToObjectMeta(c.ServiceAccountName()),
+	}
+}
+
+// toSubjects binds the service account, or the default account the pods run as when there is none, cluster
+// bindings require its namespace which defaults to default
+func (c Container) toSubjects(cluster bool) []rbacv1.Subject {
+	name := c.ServiceAccountName()
+	if name == "" {
+		name = "default"
+	}
+	namespace := c.ToNamespace()
+	if cluster && namespace == "" {
+		log.Warnf("[%s] Binding cluster rules to the service account in the default namespace, set a namespace if it is deployed elsewhere", c.Service)
+		namespace = "default"
+	}
+	return []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      name,
+		Namespace: namespace,
+	}}
+}
+
+// ToRbac returns the service account and the roles and bindings for the rbac rules
+func (c Container) ToRbac() []interface{} {
+	var specs []interface{}
+	if c.IsServiceAccountGenerated() {
+		specs = append(specs, c.ToServiceAccount())
+	}
+
+	var rules, clusterRules []rbacv1.PolicyRule
+	for _, rule := range c.Rbac {
+		if rule.Cluster {
+			clusterRules = append(clusterRules, rule.ToPolicyRules()...)
+		} else {
+			rules = append(rules, rule.ToPolicyRules()...)
+		}
+	}
+	typeMeta := func(kind string) metav1.TypeMeta {
+		return metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: kind}
+	}
+
+	if len(rules) > 0 {
+		specs = append(specs, rbacv1.Role{
+			TypeMeta:   typeMeta("Role"),
+			ObjectMeta: c.ToObjectMeta(c.Service),
+			Rules:      rules,
+		}, rbacv1.RoleBinding{
+			TypeMeta:   typeMeta("RoleBinding"),
+			ObjectMeta: c.ToObjectMeta(c.Service),
+			Subjects:   c.toSubjects(false),
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     c.Service,
+			},
+		})
+	}
+
+	if len(clusterRules) > 0 {
+		meta := c.ToObjectMeta(c.ClusterRoleName())
+		meta.Namespace = ""
+		specs = append(specs, rbacv1.ClusterRole{
+			TypeMeta:   typeMeta("ClusterRole"),
+			ObjectMeta: meta,
+			Rules:      clusterRules,
+		}, rbacv1.ClusterRoleBinding{
+			TypeMeta:   typeMeta("ClusterRoleBinding"),
+			ObjectMeta: meta,
+			Subjects:   c.toSubjects(true),
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     c.ClusterRoleName(),
+			},
+		})
+	}
+	return specs
+}
@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+)
+
+// DefaultRegistrySecret is the name of the generated docker_registry credentials unless docker_registry_secret is specified
+const DefaultRegistrySecret = "docker-registry"
+
+// applyRegistryDefaults uses the group pull secrets and policy unless the container specifies its own
+func (c *Container) applyRegistryDefaults(defaults ContainerDefaults) {
+	if c.ImagePullSecrets == nil {
+		c.ImagePullSecrets = defaults.ImagePullSecrets
+	}
+	if c.ImagePullPolicy == "" {
+		c.ImagePullPolicy = defaults.ImagePullPolicy
+	}
+}
+
+// ParseImage splits an image reference such as registry:5000/project/image:tag@sha256:... into the
+// repository, the tag and the digest, the tag follows the last ":" after the last "/"
+func ParseImage(image string) (name, tag, digest string) {
+	name = image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
+
+// ToImagePullPolicy defaults to Always for latest tags and IfNotPresent for fixed tags and digests
+func (c Container) ToImagePullPolicy() v1.PullPolicy {
+	switch strings.ToLower(c.ImagePullPolicy) {
+	case "always":
+		return v1.PullAlways
+	case "ifnotpresent", "if-not-present", "if_not_present":
+		return v1.PullIfNotPresent
+	case "never":
+		return v1.PullNever
+	}
+	if c.ImageDigest != "" {
+		return v1.PullIfNotPresent
+	}
+	if c.ImageTag == "" || c.ImageTag == "latest" {
+		return v1.PullAlways
+	}
+	return v1.PullIfNotPresent
+}
+
+// RegistrySecretName returns the name of the secret generated from the docker_registry_username
+// and docker_registry_password variables, or an empty string when there are no credentials
+func (c Container) RegistrySecretName() string {
+	if c.Group.Get("docker_registry") == "" || c.Group.Get("docker_registry_username") == "" {
+		return ""
+	}
+	if name := c.Group.Get("docker_registry_secret"); name != "" {
+		return name
+	}
+	return DefaultRegistrySecret
+}
+
+// ToRegistrySecret returns a kubernetes.io/dockerconfigjson secret for docker_registry
+func (c Container) ToRegistrySecret() v1.Secret {
+	host := strings.Split(c.Group.Get("docker_registry"), "/")[0]
+	username := c.Group.Get("docker_registry_username")
+	password := c.Group.Get("docker_registry_password")
+	auth := map[string]string{
+		"username": username,
+		"password": password,
+		"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if email := c.Group.Get("docker_registry_email"); email != "" {
+		auth["email"] = email
+	}
+	config, _ := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{host: auth},
+	})
+	return v1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: c.ToObjectMeta(c.RegistrySecretName()),
+		Type:       v1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			v1.DockerConfigJsonKey: config,
+		},
+	}
+}
+
+// ToImagePullSecrets returns the pull secrets of the pod's containers and the generated registry secret
+func (c Container) ToImagePullSecrets() []v1.LocalObjectReference {
+	names := make(map[string]bool)
+	for _, s := range append([]*Container{&c}, c.podContainers()...) {
+		for _, name := range s.ImagePullSecrets {
+			names[name] = true
+		}
+	}
+	if name := c.RegistrySecretName(); name != "" {
+		names[name] = true
+	}
+
+	var secrets []v1.LocalObjectReference
+	for name := range names {
+		secrets = append(secrets, v1.LocalObjectReference{Name: name})
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets
+}
+
+// ToSpecs returns the objects shared by the containers of the inventory, namespaces are only
+// generated when create_namespaces is enabled and the registry secret is created in every namespace
+func (inv Inventory) ToSpecs() []interface{} {
+	var namespaces, secrets []interface{}
+	seen := make(map[string]bool)
+	for _, c := range inv.Containers() {
+		namespace := c.ToNamespace()
+		if namespace != "" && c.Group.Get("create_namespaces") == "true" && !seen["namespace/"+namespace] {
+			seen["namespace/"+namespace] = true
+			namespaces = append(namespaces, c.ToNamespaceObject())
+		}
+		if name := c.RegistrySecretName(); name != "" && !seen[namespace+"/"+name] {
+			seen[namespace+"/"+name] = true
+			secrets = append(secrets, c.ToRegistrySecret())
+		}
+	}
+	return append(namespaces, secrets...)
+}
@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"strings"
+)
+
+// Resources maps resource names (cpu, memory, ephemeral-storage or extended resources such as nvidia.com/gpu) to quantities
+type Resources map[string]StringOrInt
+
+// DefaultRequestRatios are used to derive requests from limits unless the group specifies request_ratios
+var DefaultRequestRatios = map[string]float64{
+	"cpu":    0.25,
+	"memory": 0.5,
+}
+
+// ParseQuantity parses numbers (0.5) and kubernetes quantities (500m, 512Mi, 1G) alike
+func ParseQuantity(value interface{}) (resource.Quantity, error) {
+	switch v := value.(type) {
+	case int:
+		return *resource.NewQuantity(int64(v), resource.DecimalSI), nil
+	case int64:
+		return *resource.NewQuantity(v, resource.DecimalSI), nil
+	case float64:
+		return *resource.NewMilliQuantity(int64(math.Round(v*1000)), resource.DecimalSI), nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			break
+		}
+		return resource.ParseQuantity(strings.TrimSpace(v))
+	}
+	return resource.Quantity{}, errors.New("Missing quantity")
+}
+
+// IsZeroQuantity returns true for missing, invalid and zero quantities
+func IsZeroQuantity(value interface{}) bool {
+	qty, err := ParseQuantity(value)
+	return err != nil || qty.IsZero()
+}
+
+// ScaleQuantity multiplies a quantity by ratio, keeping its format
+func ScaleQuantity(q resource.Quantity, ratio float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(float64(q.MilliValue())*ratio), q.Format)
+}
+
+func ToResourceName(name string) v1.ResourceName {
+	switch strings.ToLower(name) {
+	case "mem", "memory":
+		return v1.ResourceMemory
+	case "ephemeral_storage", "ephemeral-storage":
+		return v1.ResourceEphemeralStorage
+	}
+	return v1.ResourceName(strings.ToLower(name))
+}
+
+func (r Resources) ToResourceList(service string) v1.ResourceList {
+	list := v1.ResourceList{}
+	for name, value := range r {
+		qty, err := ParseQuantity(value)
+		if err != nil {
+			log.Warnf("[%s] Invalid quantity %v for %s: %v", service, value, name, err)
+			continue
+		}
+		list[ToResourceName(name)] = qty
+	}
+	return list
+}
+
+// ToRequestRatios returns the default ratios overridden by the group's request_ratios
+func (c Container) ToRequestRatios() map[v1.ResourceName]float64 {
+	ratios := make(map[v1.ResourceName]float64)
+	for name, ratio := range DefaultRequestRatios {
+		ratios[ToResourceName(name)] = ratio
+	}
+	for name, ratio := range c.Group.ContainerDefaults.RequestRatios {
+		ratios[ToResourceName(name)] = ratio
+	}
+	return ratios
+}
+
+// ToResources uses explicit limits over mem (in Mb) and cpu, requests not specified are derived from the limits
+// using the request ratios, without a ratio kubernetes defaults the request to the limit
+func (c Container) ToResources() v1.ResourceRequirements {
+	limits := v1.ResourceList{}
+	requests := v1.ResourceList{}
+	if c.Mem > 0 {
+		limits[v1.ResourceMemory] = c.ToMem()
+	}
+	if cpu, err := c.ToCpu(); err == nil && !cpu.IsZero() {
+		limits[v1.ResourceCPU] = cpu
+	}
+	for name, qty := range c.Limits.ToResourceList(c.Service) {
+		limits[name] = qty
+	}
+
+	ratios := c.ToRequestRatios()
+	for name, limit := range limits {
+		if ratio, ok := ratios[name]; ok && ratio > 0 {
+			requests[name] = ScaleQuantity(limit, ratio)
+		}
+	}
+	for name, qty := range c.Requests.ToResourceList(c.Service) {
+		requests[name] = qty
+	}
+	for name, request := range requests {
+		if limit, ok := limits[name]; ok && request.Cmp(limit) > 0 {
+			log.Warnf("[%s] %s request %s is greater than the limit %s", c.Service, name, request.String(), limit.String())
+		}
+	}
+
+	return v1.ResourceRequirements{
+		Limits:   limits,
+		Requests: requests,
+	}
+}
+
+// applyResourceDefaults adds the group requests and limits that are not specified by the container
+func (c *Container) applyResourceDefaults(defaults ContainerDefaults) {
+	c.Requests = mergeResources(defaults.Requests, c.Requests)
+	c.Limits = mergeResources(defaults.Limits, c.Limits)
+}
+
+func mergeResources(defaults Resources, r Resources) Resources {
+	if len(defaults) == 0 {
+		return r
+	}
+	merged := make(Resources)
+	for name, value := range defaults {
+		merged[string(ToResourceName(name))] = value
+	}
+	for name, value := range r {
+		merged[string(ToResourceName(name))] = value
+	}
+	return merged
+}
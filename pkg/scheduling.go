@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"strings"
+)
+
+// DefaultGroupNodeLabel prefixes the node labels used when group_node_selector is enabled, the labels
+// are not applied by smarti, nodes must already be labelled with every group they belong to
+const DefaultGroupNodeLabel = "node.smarti.io"
+
+// Scheduling holds the pod placement settings shared by containers and container_defaults,
+// tolerations, affinity and topology_spread use the kubernetes field names
+type Scheduling struct {
+	NodeSelector   map[string]string             `json:"node_selector,omitempty"`
+	Tolerations    []v1.Toleration               `json:"tolerations,omitempty"`
+	Affinity       *v1.Affinity                  `json:"affinity,omitempty"`
+	TopologySpread []v1.TopologySpreadConstraint `json:"topology_spread,omitempty"`
+	PriorityClass  string                        `json:"priority_class,omitempty"`
+	RuntimeClass   string                        `json:"runtime_class,omitempty"`
+}
+
+// applySchedulingDefaults merges node selectors, other settings from the group are only used when not specified
+func (c *Container) applySchedulingDefaults(defaults ContainerDefaults) {
+	c.NodeSelector = mergeStrings(defaults.NodeSelector, c.NodeSelector)
+	if c.Tolerations == nil {
+		c.Tolerations = defaults.Tolerations
+	}
+	if c.Affinity == nil {
+		c.Affinity = defaults.Affinity
+	}
+	if c.TopologySpread == nil {
+		c.TopologySpread = defaults.TopologySpread
+	}
+	if c.PriorityClass == "" {
+		c.PriorityClass = defaults.PriorityClass
+	}
+	if c.RuntimeClass == "" {
+		c.RuntimeClass = defaults.RuntimeClass
+	}
+}
+
+// ToNodeSelector adds a label for the group the container is declared in when group_node_selector is enabled,
+// so that a service in group gpu only runs on nodes labelled node.smarti.io/gpu=true. The label is not derived
+// from the groups of the inventory hosts, nodes in a child group of gpu must also be labelled with gpu
+func (c Container) ToNodeSelector() map[string]string {
+	selector := mergeStrings(nil, c.NodeSelector)
+	if c.Group.Get("group_node_selector") == "true" && c.Group.Name != "" && c.Group.Name != "all" {
+		label := c.Group.Get("group_node_label")
+		if label == "" {
+			label = DefaultGroupNodeLabel
+		}
+		selector = mergeStrings(selector, map[string]string{label + "/" + c.Group.Name: "true"})
+	}
+	return selector
+}
+
+// ToAffinity uses the specified affinity, adding the preferred spreading of replicas across nodes
+// unless a pod anti-affinity is specified
+func (c Container) ToAffinity() *v1.Affinity {
+	var affinity *v1.Affinity
+	if c.Affinity != nil {
+		affinity = c.Affinity.DeepCopy()
+	}
+	if !c.IsReplicated() || (affinity != nil && affinity.PodAntiAffinity != nil) {
+		return affinity
+	}
+	if affinity == nil {
+		affinity = &v1.Affinity{}
+	}
+	affinity.PodAntiAffinity = &v1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{c.ToSpreadAffinityTerm()},
+	}
+	return affinity
+}
+
+// ToTopologySpreadConstraints defaults the skew to 1, scheduling to ScheduleAnyway and the selector to the service pods
+func (c Container) ToTopologySpreadConstraints() []v1.TopologySpreadConstraint {
+	var constraints []v1.TopologySpreadConstraint
+	for _, constraint := range c.TopologySpread {
+		if constraint.TopologyKey == "" {
+			log.Errorf("[%s] Missing topologyKey for topology_spread", c.Service)
+			continue
+		}
+		if constraint.MaxSkew == 0 {
+			constraint.MaxSkew = 1
+		}
+		if constraint.WhenUnsatisfiable == "" {
+			constraint.WhenUnsatisfiable = v1.ScheduleAnyway
+		}
+		if constraint.LabelSelector == nil {
+			constraint.LabelSelector = c.ToSelector()
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints
+}
+
+func (c Container) ToTolerations() []v1.Toleration {
+	var tolerations []v1.Toleration
+	for _, toleration := range c.Tolerations {
+		if toleration.Operator == "" && toleration.Value == "" {
+			toleration.Operator = v1.TolerationOpExists
+		}
+		toleration.Effect = ToTaintEffect(toleration.Effect)
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations
+}
+
+// ToTaintEffect accepts lowercase effects such as noschedule
+func ToTaintEffect(effect v1.TaintEffect) v1.TaintEffect {
+	for _, e := range []v1.TaintEffect{v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute} {
+		if strings.EqualFold(string(effect), string(e)) {
+			return e
+		}
+	}
+	return effect
+}
+
+func (c Container) ToRuntimeClassName() *string {
+	if c.RuntimeClass == "" {
+		return nil
+	}
+	return &c.RuntimeClass
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Secret is either generated from the values in Env and Files, or when External refers to an existing
+// secret whose keys are referenced by Env and Files
+type Secret struct {
+	Name string `json:"name,omitempty"`
+	// External secrets are referenced by name and never generated
+	External bool `json:"external,omitempty"`
+	// Env maps environment variables to values, or to keys of an external secret
+	Env map[string]string `json:"env,omitempty"`
+	// Files maps file paths to contents, or to keys of an external secret
+	Files map[string]string `json:"files,omitempty"`
+	// EnvFrom exposes every key of the secret as an environment variable
+	EnvFrom bool `json:"env_from,omitempty"`
+	// Path mounts every key of the secret as a file in this directory
+	Path string `json:"path,omitempty"`
+	// Template renders every value and file as a template, otherwise only env values containing {{ are rendered
+	Template bool `json:"template,omitempty"`
+}
+
+func (c Container) SecretName(secret Secret) string {
+	if secret.Name != "" {
+		return secret.Name
+	}
+	return c.Service + "-secret"
+}
+
+// key returns the key that a file is stored under in the secret
+func (secret Secret) key(file string) string {
+	if secret.External {
+		return secret.Files[file]
+	}
+	return path.Base(file)
+}
+
+// applySecretDefaults names the generated secrets without a name after the service, numbering them when there
+// are several so that their secrets and volumes don't collide, and renders their templated values
+func (c *Container) applySecretDefaults() {
+	var unnamed []int
+	for i, secret := range c.Secrets {
+		if secret.Name == "" && !secret.External {
+			unnamed = append(unnamed, i)
+		}
+	}
+	for n, i := range unnamed {
+		c.Secrets[i].Name = c.Service + "-secret"
+		if len(unnamed) > 1 {
+			c.Secrets[i].Name += fmt.Sprintf("-%d", n+1)
+		}
+	}
+	c.interpolateSecrets()
+}
+
+// interpolateSecrets renders the templated values of generated secrets
+func (c *Container) interpolateSecrets() {
+	for _, secret := range c.Secrets {
+		if secret.External {
+			continue
+		}
+		for k, v := range secret.Env {
+			if secret.Template || strings.Contains(v, "{{") {
+				secret.Env[k] = c.renderSecret(secret, k, "", v)
+			}
+		}
+		if !secret.Template {
+			continue
+		}
+		for k, v := range secret.Files {
+			secret.Files[k] = c.renderSecret(secret, k, k, v)
+		}
+	}
+}
+
+// renderSecret returns the rendered value and fails rather than storing the template source as the secret,
+// errors never include the value as it is secret
+func (c Container) renderSecret(secret Secret, key string, file string, value string) string {
+	out, err := RenderTemplate(TemplateEngine(file, c.Group.Vars), value, c.Group.Vars)
+	if err != nil {
+		log.Fatalf("[%s] Error rendering %s in secret %s: %v", c.Service, key, c.SecretName(secret), err)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ToSecrets returns the secrets to generate, external secrets are skipped
+func (c Container) ToSecrets() []interface{} {
+	var secrets []interface{}
+	for _, secret := range c.Secrets {
+		if secret.External {
+			continue
+		}
+		data := make(map[string][]byte)
+		for name, value := range secret.Env {
+			data[name] = []byte(value)
+		}
+		for file, content := range secret.Files {
+			data[secret.key(file)] = []byte(content)
+		}
+		secrets = append(secrets, v1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Secret",
+			},
+			ObjectMeta: c.ToObjectMeta(c.SecretName(secret)),
+			Type: v1.SecretTypeOpaque,
+			Data: data,
+		})
+	}
+	return secrets
+}
+
+func (c Container) ToSecretEnvVars() []v1.EnvVar {
+	var vars []v1.EnvVar
+	for _, secret := range c.Secrets {
+		if secret.EnvFrom && !secret.External {
+			// all generated keys are already exposed by envFrom
+			continue
+		}
+		for _, name := range sortedKeys(secret.Env) {
+			key := name
+			if secret.External {
+				key = secret.Env[name]
+			}
+			vars = append(vars, v1.EnvVar{
+				Name: name,
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: c.SecretName(secret)},
+						Key:                  key,
+					},
+				},
+			})
+		}
+	}
+	return vars
+}
+
+func (c Container) ToSecretEnvFrom() []v1.EnvFromSource {
+	var sources []v1.EnvFromSource
+	for _, secret := range c.Secrets {
+		if secret.EnvFrom {
+			sources = append(sources, v1.EnvFromSource{
+				SecretRef: &v1.SecretEnvSource{
+					LocalObjectReference: v1.LocalObjectReference{Name: c.SecretName(secret)},
+				},
+			})
+		}
+	}
+	return sources
+}
+
+func (c Container) ToSecretVolumes() []v1.Volume {
+	var volumes []v1.Volume
+	for _, secret := range c.Secrets {
+		if len(secret.Files) == 0 && secret.Path == "" {
+			continue
+		}
+		volumes = append(volumes, v1.Volume{
+			Name: ConfigMapName(c.SecretName(secret)),
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: c.SecretName(secret),
+				},
+			},
+		})
+	}
+	return volumes
+}
+
+// ToSecretVolumeMounts mounts each file individually using a subPath so that other files in the
+// same directory are not hidden
+func (c Container) ToSecretVolumeMounts() []v1.VolumeMount {
+	var mounts []v1.VolumeMount
+	for _, secret := range c.Secrets {
+		name := ConfigMapName(c.SecretName(secret))
+		if secret.Path != "" {
+			mounts = append(mounts, v1.VolumeMount{
+				Name:      name,
+				MountPath: secret.Path,
+				ReadOnly:  true,
+			})
+		}
+		for _, file := range sortedKeys(secret.Files) {
+			mounts = append(mounts, v1.VolumeMount{
+				Name:      name,
+				MountPath: file,
+				SubPath:   secret.key(file),
+				ReadOnly:  true,
+			})
+		}
+	}
+	return mounts
+}
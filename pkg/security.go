@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"strconv"
+	"strings"
+)
+
+// Security holds the security settings shared by containers and container_defaults
+type Security struct {
+	ReadOnlyRootFs           *bool         `json:"read_only_root_fs,omitempty"`
+	RunAsNonRoot             *bool         `json:"run_as_non_root,omitempty"`
+	AllowPrivilegeEscalation *bool         `json:"allow_privilege_escalation,omitempty"`
+	FsGroup                  *int64        `json:"fs_group,omitempty"`
+	Capabilities             *Capabilities `json:"capabilities,omitempty"`
+	Seccomp                  string        `json:"seccomp,omitempty"`
+}
+
+type Capabilities struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
+}
+
+// applySecurityDefaults fills in the security settings not specified by the container
+func (c *Container) applySecurityDefaults(defaults ContainerDefaults) {
+	if c.User == "" {
+		c.User = defaults.User
+	}
+	if c.ReadOnlyRootFs == nil {
+		c.ReadOnlyRootFs = defaults.ReadOnlyRootFs
+	}
+	if c.RunAsNonRoot == nil {
+		c.RunAsNonRoot = defaults.RunAsNonRoot
+	}
+	if c.AllowPrivilegeEscalation == nil {
+		c.AllowPrivilegeEscalation = defaults.AllowPrivilegeEscalation
+	}
+	if c.FsGroup == nil {
+		c.FsGroup = defaults.FsGroup
+	}
+	if c.Capabilities == nil {
+		c.Capabilities = defaults.Capabilities
+	}
+	if c.Seccomp == "" {
+		c.Seccomp = defaults.Seccomp
+	}
+}
+
+// ToID resolves a numeric id, root or a name listed in the uids variable
+func (c Container) ToID(name string) (*int64, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if id, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return &id, nil
+	}
+	if name == "root" {
+		id := int64(0)
+		return &id, nil
+	}
+	if uids, ok := c.Group.Vars["uids"].(map[string]interface{}); ok {
+		if uid, ok := uids[name]; ok {
+			id, err := strconv.ParseInt(fmt.Sprintf("%v", uid), 10, 64)
+			return &id, err
+		}
+	}
+	return nil, fmt.Errorf("unknown user or group %s, kubernetes requires numeric ids (or add it to uids)", name)
+}
+
+// ToRunAsUser parses user in the uid, uid:gid or name:group form
+func (c Container) ToRunAsUser() (*int64, *int64) {
+	if c.User == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(c.User, ":", 2)
+	uid, err := c.ToID(parts[0])
+	if err != nil {
+		log.Warnf("[%s] %v", c.Service, err)
+	}
+	var gid *int64
+	if len(parts) > 1 {
+		if gid, err = c.ToID(parts[1]); err != nil {
+			log.Warnf("[%s] %v", c.Service, err)
+		}
+	}
+	return uid, gid
+}
+
+func ToCapabilities(names []string) []v1.Capability {
+	var capabilities []v1.Capability
+	for _, name := range names {
+		capabilities = append(capabilities, v1.Capability(strings.TrimPrefix(strings.ToUpper(name), "CAP_")))
+	}
+	return capabilities
+}
+
+func (c Container) ToSecurityContext() *v1.SecurityContext {
+	uid, gid := c.ToRunAsUser()
+	if uid == nil && gid == nil && !c.Privileged && c.ReadOnlyRootFs == nil && c.RunAsNonRoot == nil &&
+		c.AllowPrivilegeEscalation == nil && c.Capabilities == nil {
+		return nil
+	}
+	context := &v1.SecurityContext{
+		RunAsUser:                uid,
+		RunAsGroup:               gid,
+		RunAsNonRoot:             c.RunAsNonRoot,
+		ReadOnlyRootFilesystem:   c.ReadOnlyRootFs,
+		AllowPrivilegeEscalation: c.AllowPrivilegeEscalation,
+	}
+	if c.Privileged {
+		context.Privileged = &c.Privileged
+		if c.AllowPrivilegeEscalation != nil && !*c.AllowPrivilegeEscalation {
+			log.Warnf("[%s] Privileged containers always allow privilege escalation", c.Service)
+			context.AllowPrivilegeEscalation = nil
+		}
+	}
+	if c.Capabilities != nil {
+		context.Capabilities = &v1.Capabilities{
+			Add:  ToCapabilities(c.Capabilities.Add),
+			Drop: ToCapabilities(c.Capabilities.Drop),
+		}
+	}
+	if uid != nil && *uid == 0 && c.RunAsNonRoot != nil && *c.RunAsNonRoot {
+		log.Warnf("[%s] run_as_non_root is set but the container runs as root", c.Service)
+	}
+	return context
+}
+
+// ToSeccompProfile accepts the docker (runtime/default, unconfined, localhost/<profile>) and kubernetes names
+func (c Container) ToSeccompProfile() *v1.SeccompProfile {
+	switch strings.ToLower(c.Seccomp) {
+	case "":
+		return nil
+	case "runtime/default", "runtimedefault", "default":
+		return &v1.SeccompProfile{Type: v1.SeccompProfileTypeRuntimeDefault}
+	case "unconfined":
+		return &v1.SeccompProfile{Type: v1.SeccompProfileTypeUnconfined}
+	}
+	profile := strings.TrimPrefix(c.Seccomp, "localhost/")
+	return &v1.SeccompProfile{
+		Type:             v1.SeccompProfileTypeLocalhost,
+		LocalhostProfile: &profile,
+	}
+}
+
+// ToPodSecurityContext contains the settings that apply to every container in the pod
+func (c Container) ToPodSecurityContext() *v1.PodSecurityContext {
+	if c.FsGroup == nil && c.Seccomp == "" {
+		return nil
+	}
+	return &v1.PodSecurityContext{
+		FSGroup:        c.FsGroup,
+		SeccompProfile: c.ToSeccompProfile(),
+	}
+}
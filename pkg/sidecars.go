@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+// processSidecars adds the default sidecars of the group and post processes all the sidecars and init containers
+func (c *Container) processSidecars() {
+	for _, s := range c.podContainers() {
+		s.ContainerName = s.ToContainerName()
+	}
+	for _, def := range c.Group.ContainerDefaults.Sidecars {
+		if c.hasSidecar(def.ToContainerName()) {
+			continue
+		}
+		// every pod needs its own copy as post processing modifies the container
+		sidecar := new(Container)
+		if err := deepCopy(sidecar, def); err != nil {
+			log.Warnf("[%s] Invalid default sidecar %s: %v", c.Service, def.ContainerName, err)
+			continue
+		}
+		c.Sidecars = append(c.Sidecars, sidecar)
+	}
+
+	for _, s := range c.InitContainers {
+		s.initContainer = true
+	}
+	for _, s := range c.podContainers() {
+		s.Group = c.Group
+		// default sidecars and autoscaling only apply to the pod's main container
+		s.Group.ContainerDefaults.Sidecars = nil
+		s.Group.ContainerDefaults.Autoscale = nil
+		if s.initContainer {
+			// the api server rejects probes and lifecycle hooks on init containers
+			s.Group.ContainerDefaults.ReadinessProbe = nil
+			s.Group.ContainerDefaults.LivenessProbe = nil
+			s.Group.ContainerDefaults.StartupProbe = nil
+			if len(s.Commands) > 0 {
+				log.Warnf("[%s] Ignoring commands of init container %s, init containers cannot have lifecycle hooks", c.Service, s.ContainerName)
+				s.Commands = nil
+			}
+		}
+		s.ContainerName = s.ToContainerName()
+		// sidecars don't get their own service, but config maps, secrets and claims are named after it
+		s.Service = c.Service + "-" + s.ContainerName
+		s.Namespace = c.Namespace
+		s.PostProcess()
+		s.Kind = c.Kind
+		s.Replicas = c.Replicas
+	}
+}
+
+// ToContainerName returns the container_name, defaulting to the name of the image
+func (c Container) ToContainerName() string {
+	if c.ContainerName != "" {
+		return c.ContainerName
+	}
+	name, _, _ := ParseImage(c.Image)
+	return ToName(name)
+}
+
+// podContainers returns the sidecars and init containers sharing the pod with the container
+func (c Container) podContainers() []*Container {
+	return append(append([]*Container{}, c.Sidecars...), c.InitContainers...)
+}
+
+func (c Container) hasSidecar(name string) bool {
+	for _, s := range c.Sidecars {
+		if s.ContainerName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ToSidecarSpecs returns the config maps, secrets and claims required by the sidecars and init containers
+func (c *Container) ToSidecarSpecs() []interface{} {
+	var specs []interface{}
+	// work on copies so that the generated config map volumes are not added twice
+	c.Sidecars = copyContainers(c.Sidecars)
+	c.InitContainers = copyContainers(c.InitContainers)
+	shared := make(map[string]bool)
+	for _, vol := range c.Volumes {
+		shared[vol.ToName()] = true
+	}
+	for _, s := range c.podContainers() {
+		// config maps are named after their directory, which the main container may also use
+		specs = append(specs, s.toConfigMaps(s.Service+"-")...)
+		specs = append(specs, s.ToSecrets()...)
+		// volumes already declared in the pod are shared, so only claim the new ones
+		owned := *s
+		owned.Volumes = nil
+		for _, vol := range s.Volumes {
+			if !shared[vol.ToName()] {
+				shared[vol.ToName()] = true
+				owned.Volumes = append(owned.Volumes, vol)
+			}
+		}
+		specs = append(specs, owned.ToPersistentVolumeClaims()...)
+	}
+	return specs
+}
+
+func copyContainers(containers []*Container) []*Container {
+	var copies []*Container
+	for _, c := range containers {
+		cp := *c
+		copies = append(copies, &cp)
+	}
+	return copies
+}
+
+func ToContainers(containers []*Container) []v1.Container {
+	var specs []v1.Container
+	for _, c := range containers {
+		specs = append(specs, c.ToContainer())
+	}
+	return specs
+}
+
+// ToPodVolumes returns the volumes of the container, its sidecars and init containers, volumes with the same name are shared
+func (c Container) ToPodVolumes() []v1.Volume {
+	volumes := append(append(c.K8Volumes, c.ToVolumes()...), c.ToSecretVolumes()...)
+	for _, s := range c.podContainers() {
+		volumes = append(volumes, s.K8Volumes...)
+		volumes = append(volumes, s.ToVolumes()...)
+		volumes = append(volumes, s.ToSecretVolumes()...)
+	}
+
+	var unique []v1.Volume
+	names := make(map[string]bool)
+	for _, vol := range volumes {
+		if names[vol.Name] {
+			continue
+		}
+		names[vol.Name] = true
+		unique = append(unique, vol)
+	}
+	return unique
+}
+
+// ToPodVolumeClaimTemplates returns the claim templates of the container, its sidecars and init containers
+func (c Container) ToPodVolumeClaimTemplates() []v1.PersistentVolumeClaim {
+	claims := c.ToVolumeClaimTemplates()
+	names := make(map[string]bool)
+	for _, claim := range claims {
+		names[claim.Name] = true
+	}
+	for _, s := range c.podContainers() {
+		for _, claim := range s.ToVolumeClaimTemplates() {
+			if !names[claim.Name] {
+				names[claim.Name] = true
+				claims = append(claims, claim)
+			}
+		}
+	}
+	return claims
+}
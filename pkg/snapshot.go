@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+const SnapshotVersion = "smarti/v1"
+
+// Snapshot is a fully resolved inventory that can be rendered without access to the original
+// inventory, files or registries
+type Snapshot struct {
+	APIVersion    string                 `json:"apiVersion"`
+	Kind          string                 `json:"kind"`
+	SmartiVersion string                 `json:"smartiVersion"`
+	Created       time.Time              `json:"created"`
+	Limit         string                 `json:"limit,omitempty"`
+	Vars          map[string]interface{} `json:"vars,omitempty"`
+	Groups        map[string]*Group      `json:"groups"`
+	Hosts         map[string]*Host       `json:"hosts,omitempty"`
+}
+
+// NewSnapshot resolves all container files and templates so that the snapshot is self contained
+func NewSnapshot(inv Inventory) Snapshot {
+	for _, c := range inv.Containers() {
+		c.ConfigData = c.ToConfigData()
+	}
+	return Snapshot{
+		APIVersion:    SnapshotVersion,
+		Kind:          "Snapshot",
+		SmartiVersion: VERSION,
+		Created:       time.Now().UTC(),
+		Limit:         inv.Limit,
+		Vars:          inv.Vars,
+		Groups:        inv.Groups,
+		Hosts:         inv.Hosts,
+	}
+}
+
+// SnapshotSecrets returns the values that a snapshot stores in plain text: decrypted vault values,
+// the values of generated secrets and docker_registry_password
+func (inv Inventory) SnapshotSecrets() []string {
+	names := make(map[string]bool)
+	for name := range inv.Decrypted {
+		names[name] = true
+	}
+	for _, c := range inv.Containers() {
+		for _, container := range append([]*Container{c}, c.podContainers()...) {
+			for _, secret := range container.Secrets {
+				if !secret.External && len(secret.Env)+len(secret.Files) > 0 {
+					names["secret "+container.SecretName(secret)] = true
+				}
+			}
+		}
+	}
+	if inv.Vars["docker_registry_password"] != nil {
+		names["docker_registry_password"] = true
+	}
+	for _, group := range inv.Groups {
+		if group.Vars["docker_registry_password"] != nil {
+			names["docker_registry_password"] = true
+		}
+	}
+	var secrets []string
+	for name := range names {
+		secrets = append(secrets, name)
+	}
+	sort.Strings(secrets)
+	return secrets
+}
+
+// LoadSnapshot reads a snapshot written by smarti export and restores the links between groups and containers
+func LoadSnapshot(file string) (Inventory, error) {
+	inv := NewInventory()
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return inv, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return inv, fmt.Errorf("invalid snapshot %s: %v", file, err)
+	}
+	if snapshot.APIVersion != SnapshotVersion {
+		return inv, fmt.Errorf("unsupported snapshot version %s in %s, expected %s", snapshot.APIVersion, file, SnapshotVersion)
+	}
+	log.Infof("Loading snapshot created %s by smarti %s", snapshot.Created, snapshot.SmartiVersion)
+
+	inv.Limit = snapshot.Limit
+	if snapshot.Vars != nil {
+		inv.Vars = snapshot.Vars
+	}
+	if snapshot.Hosts != nil {
+		inv.Hosts = snapshot.Hosts
+	}
+	for name, group := range snapshot.Groups {
+		group.Inventory = &inv
+		for _, c := range group.Containers {
+			c.Group = *group
+			for _, s := range c.podContainers() {
+				s.Group = *group
+			}
+		}
+		inv.Groups[name] = group
+	}
+	return inv, nil
+}
+
+// LoadInventory parses the inventory, or loads it from --from-snapshot when specified
+func LoadInventory(cmd *cobra.Command) Inventory {
+	if flag := cmd.Flag("from-snapshot"); flag != nil && flag.Value.String() != "" {
+		inv, err := LoadSnapshot(flag.Value.String())
+		if err != nil {
+			log.Fatal(err)
+		}
+		inv.Limit = cmd.Flag("limit").Value.String()
+		ParseFlagVars(cmd, inv)
+		for group := range inv.Groups {
+			if inv.IsLimited(group) {
+				log.Infof("Excluding %s", group)
+				delete(inv.Groups, group)
+			}
+		}
+		return inv
+	}
+	return Parse(cmd)
+}
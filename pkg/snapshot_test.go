@@ -0,0 +1,138 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const snapshotInventory = `
+kube_version: "1.22"
+containers:
+  - image: api:1.2
+    ports: ["8080"]
+    env:
+      MODE: production
+    ingress: api.example.com
+  - image: worker:3
+    replicas: 2
+    depends_on: [api]
+`
+
+// renderSpecs returns the specs of every container by service
+func renderSpecs(inv Inventory) map[string]string {
+	specs := make(map[string]string)
+	for _, c := range inv.Containers() {
+		specs[c.Service] = MarshalSpecs(c.ToSpecs(), "yaml")
+	}
+	return specs
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	inv := testInventory(t, map[string]string{"all": snapshotInventory})
+	expected := renderSpecs(inv)
+	data, err := json.Marshal(NewSnapshot(inv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := ioutil.WriteFile(file, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadSnapshot(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := renderSpecs(loaded)
+	if len(actual) != 2 {
+		t.Fatalf("expected the api and worker containers, got %v", reflect.ValueOf(actual).MapKeys())
+	}
+	for service, spec := range expected {
+		if actual[service] != spec {
+			t.Errorf("%s: the snapshot renders\n%s\nexpected\n%s", service, actual[service], spec)
+		}
+	}
+	if !strings.Contains(actual["api"], "value: production") {
+		t.Errorf("expected the env in the snapshot, got\n%s", actual["api"])
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid json", "{"},
+		{"unsupported version", `{"apiVersion": "smarti/v0", "kind": "Snapshot"}`},
+	}
+	for _, test := range tests {
+		file := filepath.Join(dir, strings.Replace(test.name, " ", "-", -1)+".json")
+		if err := ioutil.WriteFile(file, []byte(test.data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSnapshot(file); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+	if _, err := LoadSnapshot(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
+
+func TestSnapshotSecrets(t *testing.T) {
+	tests := []struct {
+		name      string
+		vars      string
+		decrypted []string
+		expected  []string
+	}{
+		{"no secrets", snapshotInventory, nil, nil},
+		{"vault value", snapshotInventory, []string{"db_password"}, []string{"db_password"}},
+		{"generated secret", `
+containers:
+  - image: api:1
+    secrets:
+      - env:
+          TOKEN: "{{ lookup('env', 'HOME') }}"
+`, nil, []string{"secret api-secret"}},
+		{"external secret", `
+containers:
+  - image: api:1
+    secrets:
+      - name: api-token
+        external: true
+        env:
+          TOKEN: token
+`, nil, nil},
+		{"sidecar secret", `
+containers:
+  - image: api:1
+    sidecars:
+      - image: proxy:1
+        secrets:
+          - env:
+              KEY: key
+`, nil, []string{"secret api-proxy-secret"}},
+		{"registry password", `
+docker_registry: registry.example.com
+docker_registry_username: deploy
+docker_registry_password: hunter2
+containers:
+  - image: api:1
+`, nil, []string{"docker_registry_password"}},
+	}
+	for _, test := range tests {
+		inv := testInventory(t, map[string]string{"all": test.vars})
+		for _, name := range test.decrypted {
+			inv.Decrypted[name] = true
+		}
+		if secrets := inv.SnapshotSecrets(); !reflect.DeepEqual(secrets, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, secrets)
+		}
+	}
+}
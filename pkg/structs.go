@@ -1,25 +1,24 @@
 package pkg
 
 import (
-	"github.com/mitchellh/mapstructure"
 	log "github.com/sirupsen/logrus"
 	"strings"
 	"fmt"
 )
 
 type Group struct {
-	Name         string
-	ParentGroups [] string
-	Vars         map[string]interface{}
-	Containers 	 []*Container
-	ContainerDefaults ContainerDefaults
-	Inventory 	*Inventory
+	Name              string                 `json:"name"`
+	ParentGroups      []string               `json:"parent_groups,omitempty"`
+	Vars              map[string]interface{} `json:"vars,omitempty"`
+	Containers        []*Container           `json:"containers,omitempty"`
+	ContainerDefaults ContainerDefaults      `json:"container_defaults"`
+	Inventory         *Inventory             `json:"-"`
 }
 
 type Host struct {
-	Name   string
-	Groups []Group
-	Vars   map[string]interface{}
+	Name   string                 `json:"name"`
+	Groups []Group                `json:"groups,omitempty"`
+	Vars   map[string]interface{} `json:"vars,omitempty"`
 }
 
 type Inventory struct {
@@ -27,6 +26,19 @@ type Inventory struct {
 	Hosts  map[string]*Host
 	Vars   map[string]interface{}
 	Limit string
+	HashBehaviour string
+	VaultPassword string
+	// Decrypted records the vault encrypted files and variables that were decrypted
+	Decrypted map[string]bool
+}
+
+// PutAll copies src into dst, recursively merging dictionaries when hash_behaviour=merge
+func (inv Inventory) PutAll(src map[string]interface{}, dst map[string]interface{}) {
+	if inv.HashBehaviour == "merge" {
+		MergeAll(src, dst)
+	} else {
+		PutAll(src, dst)
+	}
 }
 
 
@@ -51,13 +63,10 @@ func (inv Inventory) AddGroup(group Group) {
 	}
 	defaults, ok := group.Vars["container_defaults"];
 	if  ok {
-		mapstructure.Decode(defaults,&group.ContainerDefaults )
-		//FIXME .Decode for some reason doesn't pick up service_type
-		service_type, ok  := defaults.(map[string]interface{})["service_type"]
-		if ok {
-			group.ContainerDefaults.ServiceType = service_type.(string)
+		// decode like containers so that defaults (and default sidecars) use the same keys
+		if err := deepCopy(&group.ContainerDefaults, defaults); err != nil {
+			log.Warnf("Invalid container_defaults in %s: %v", group.Name, err)
 		}
-
 	}
 	inv.Groups[group.Name] = &group
 	group.Inventory = &inv
@@ -98,19 +107,19 @@ func (inv Inventory) Merge() {
 	vars := inv.Vars
 	all := groups["all"]
 
-	PutAll(vars, all.Vars)
+	inv.PutAll(vars, all.Vars)
 
 	for _, group := range groups {
 		vars := make(map[string]interface{})
 		PutAll(all.Vars, vars)
 		for _, parent := range group.ParentGroups {
 			if group, ok := groups[parent]; ok {
-				PutAll(group.Vars, vars)
+				inv.PutAll(group.Vars, vars)
 			} else {
 				log.Warningf("Missing group %s", parent)
 			}
 		}
-		PutAll(group.Vars, vars)
+		inv.PutAll(group.Vars, vars)
 		PutAll(vars, group.Vars)
 		//group.Vars = vars
 		//println(fmt.Sprintf("%v",group.Vars))
@@ -133,5 +142,6 @@ func NewInventory() Inventory {
 	inv.Vars = make(map[string]interface{})
 	inv.Groups = make(map[string]*Group)
 	inv.Hosts = make(map[string]*Host)
+	inv.Decrypted = make(map[string]bool)
 	return inv
 }
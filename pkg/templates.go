@@ -0,0 +1,121 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/Masterminds/sprig"
+	"github.com/flosch/pongo2"
+)
+
+const (
+	JinjaEngine = "jinja"
+	GoEngine    = "go"
+)
+
+// TemplateEngine returns the engine used to render the template called name, a recognised file
+// extension takes precedence over the template_engine variable which defaults to jinja
+func TemplateEngine(name string, vars map[string]interface{}) string {
+	switch path.Ext(name) {
+	case ".gotmpl", ".tmpl", ".tpl":
+		return GoEngine
+	case ".j2", ".jinja", ".jinja2":
+		return JinjaEngine
+	}
+	if engine, ok := vars["template_engine"]; ok && strings.ToLower(fmt.Sprintf("%v", engine)) == GoEngine {
+		return GoEngine
+	}
+	return JinjaEngine
+}
+
+// RenderTemplate renders text using the specified engine with vars as the context
+func RenderTemplate(engine string, text string, vars map[string]interface{}) (string, error) {
+	if engine == GoEngine {
+		return renderGoTemplate(text, vars)
+	}
+	return renderJinjaTemplate(text, vars)
+}
+
+func renderJinjaTemplate(text string, vars map[string]interface{}) (string, error) {
+	return renderPongoTemplate(ConvertSyntaxFromJinjaToPongo(text), vars)
+}
+
+// renderPongoTemplate renders text as is, without converting jinja filter syntax
+func renderPongoTemplate(text string, vars map[string]interface{}) (string, error) {
+	tpl, err := pongo2.FromString(text)
+	if err != nil {
+		return "", err
+	}
+	return tpl.Execute(vars)
+}
+
+func GoTemplateFuncs() template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	for name, fn := range goFilters() {
+		funcs[name] = fn
+	}
+	funcs["lookup"] = LookupValue
+	funcs[noValueFunc] = noValue
+	return funcs
+}
+
+// noValueFunc is appended to the pipeline of every action so that missing variables render as empty strings
+// to match the jinja engine, text/template prints <no value> for them even with missingkey=zero
+const noValueFunc = "noValue"
+
+func noValue(value interface{}) interface{} {
+	if value == nil {
+		return ""
+	}
+	return value
+}
+
+func appendNoValue(node parse.Node) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			appendNoValue(n)
+		}
+	case *parse.ActionNode:
+		if len(node.Pipe.Decl) == 0 {
+			node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      node.Pos,
+				Args:     []parse.Node{parse.NewIdentifier(noValueFunc).SetPos(node.Pos)},
+			})
+		}
+	case *parse.IfNode:
+		appendNoValue(node.List)
+		appendNoValue(node.ElseList)
+	case *parse.RangeNode:
+		appendNoValue(node.List)
+		appendNoValue(node.ElseList)
+	case *parse.WithNode:
+		appendNoValue(node.List)
+		appendNoValue(node.ElseList)
+	}
+}
+
+func renderGoTemplate(text string, vars map[string]interface{}) (string, error) {
+	tpl, err := template.New("").Funcs(GoTemplateFuncs()).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tpl.Templates() {
+		if t.Tree != nil {
+			appendNoValue(t.Tree.Root)
+		}
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,79 @@
+package pkg
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]interface{}{
+		"name":  "web",
+		"empty": nil,
+		"svc":   map[string]interface{}{"port": 80},
+		"hosts": []interface{}{"a", "b"},
+	}
+	tests := []struct {
+		name     string
+		engine   string
+		text     string
+		expected string
+	}{
+		{"go variable", GoEngine, "{{ .name }}", "web"},
+		{"go missing variable", GoEngine, "a={{ .missing }}", "a="},
+		{"go missing nested variable", GoEngine, "{{ .svc.host }}:{{ .svc.port }}", ":80"},
+		{"go nil variable", GoEngine, "{{ .empty }}", ""},
+		{"go missing in if and range", GoEngine, "{{ if .name }}{{ .missing }}{{ end }}{{ range .hosts }}[{{ . }}{{ $.missing }}]{{ end }}", "[a][b]"},
+		{"go missing in with else", GoEngine, "{{ with .missing }}x{{ else }}{{ .missing }}y{{ end }}", "y"},
+		{"go missing in defined template", GoEngine, `{{ define "t" }}{{ .missing }}{{ end }}{{ template "t" . }}`, ""},
+		{"go default of missing", GoEngine, `{{ .missing | default "x" }}`, "x"},
+		{"go pipeline", GoEngine, `{{ .name | printf "%s!" }}`, "web!"},
+		{"go assignment", GoEngine, "{{ $v := .name }}{{ $v }}", "web"},
+		{"go literal no value", GoEngine, "<no value>", "<no value>"},
+		{"jinja variable", JinjaEngine, "{{ name }}", "web"},
+		{"jinja missing variable", JinjaEngine, "a={{ missing }}", "a="},
+		{"jinja filter", JinjaEngine, "{{ name | upper }}", "WEB"},
+		{"jinja default filter", JinjaEngine, "{{ missing | default('x') }}", "x"},
+		{"jinja literal parentheses", JinjaEngine, "p(a)ss {{ name }}", "p(a)ss web"},
+	}
+	for _, test := range tests {
+		out, err := RenderTemplate(test.engine, test.text, vars)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if out != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, out)
+		}
+	}
+}
+
+func TestRenderTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		engine string
+		text   string
+	}{
+		{"go unclosed action", GoEngine, "{{ .name "},
+		{"go unknown function", GoEngine, "{{ nosuchfunc .name }}"},
+		{"jinja unknown filter", JinjaEngine, "{{ name | nosuchfilter }}"},
+	}
+	for _, test := range tests {
+		if out, err := RenderTemplate(test.engine, test.text, map[string]interface{}{"name": "web"}); err == nil {
+			t.Errorf("%s: expected an error, got %q", test.name, out)
+		}
+	}
+}
+
+func TestTemplateEngine(t *testing.T) {
+	tests := []struct {
+		file     string
+		vars     map[string]interface{}
+		expected string
+	}{
+		{"app.conf", nil, JinjaEngine},
+		{"app.conf", map[string]interface{}{"template_engine": "go"}, GoEngine},
+		{"app.conf.tmpl", nil, GoEngine},
+		{"app.conf.gotmpl", nil, GoEngine},
+		{"app.conf.j2", map[string]interface{}{"template_engine": "go"}, JinjaEngine},
+	}
+	for _, test := range tests {
+		if engine := TemplateEngine(test.file, test.vars); engine != test.expected {
+			t.Errorf("%s %v: expected %s, got %s", test.file, test.vars, test.expected, engine)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package pkg
 
 import (
 	"bytes"
+	"encoding/json"
+	"gopkg.in/ini.v1"
 	"syscall"
 	"fmt"
 	"os/exec"
@@ -89,4 +91,32 @@ func homeDir() string {
 		return h
 	}
 	return os.Getenv("USERPROFILE") // windows
-}
\ No newline at end of file
+}
+// deepCopy copies src into dst via json, so that dst is decoded with its own json tags
+func deepCopy(dst interface{}, src interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// LoadIni parses ini content, keys without a value are allowed as in ansible inventories
+func LoadIni(content string) (*ini.File, error) {
+	return ini.LoadSources(ini.LoadOptions{AllowBooleanKeys: true}, []byte(content))
+}
+
+// IniMapFlat returns the keys of all sections as section.key, keys outside of a section are returned as is
+func IniMapFlat(cfg *ini.File) map[string]string {
+	flat := make(map[string]string)
+	for _, section := range cfg.Sections() {
+		for _, key := range section.Keys() {
+			if section.Name() == ini.DefaultSection {
+				flat[key.Name()] = key.String()
+			} else {
+				flat[section.Name()+"."+key.Name()] = key.String()
+			}
+		}
+	}
+	return flat
+}
@@ -1,10 +1,10 @@
 package pkg
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"os"
-	"github.com/knq/ini"
 	"github.com/ghodss/yaml"
 	"io/ioutil"
 	log "github.com/sirupsen/logrus"
@@ -29,7 +29,7 @@ func FindImports(bytes []byte, inventory Inventory) map[string]interface{} {
 		dst := pwd + "/.getter/" + regexp.MustCompile("[^0-9a-zA-z]*").ReplaceAllString(source, "")
 		err := getter.Get(dst, source)
 		if err != nil {
-			log.Fatal("Error retrieving %s: \n %s", source, err)
+			log.Fatalf("Error retrieving %s: \n %s", source, err)
 			return vars
 		}
 
@@ -50,6 +50,15 @@ func FindImports(bytes []byte, inventory Inventory) map[string]interface{} {
 func ParseFile(file string, inventory Inventory) map[string]interface{} {
 	log.Debugf("Parsing %s", file)
 	bytes, err := ioutil.ReadFile(file)
+	if err == nil && IsVaultEncrypted(string(bytes)) {
+		var plaintext string
+		if plaintext, err = VaultDecrypt(string(bytes), inventory.VaultPassword); err == nil {
+			bytes = []byte(plaintext)
+			inventory.Decrypted[file] = true
+		} else {
+			err = fmt.Errorf("error decrypting %s: %v", file, err)
+		}
+	}
 	vars := FindImports(bytes, inventory)
 	if err != nil {
 		log.Error(err)
@@ -60,11 +69,11 @@ func ParseFile(file string, inventory Inventory) map[string]interface{} {
 		json.Unmarshal(bytes, &vars)
 	} else if strings.HasSuffix(file, "properties") || strings.HasSuffix(file, "ini") {
 
-		cfg, err := ini.LoadString(string(bytes))
+		cfg, err := LoadIni(string(bytes))
 		if err != nil {
 			panic(err)
 		}
-		for k,v := range cfg.GetMapFlat() {
+		for k,v := range IniMapFlat(cfg) {
 			vars[k] = string(v)
 		}
 	} else {
@@ -72,5 +81,6 @@ func ParseFile(file string, inventory Inventory) map[string]interface{} {
 
 	}
 
+	inventory.DecryptVars(vars)
 	return vars
 }
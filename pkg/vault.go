@@ -0,0 +1,140 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/pbkdf2"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const vaultHeader = "$ANSIBLE_VAULT"
+
+// IsVaultEncrypted returns true if data is an ansible-vault payload, either a whole file or an inline !vault value
+func IsVaultEncrypted(data string) bool {
+	return strings.HasPrefix(strings.TrimSpace(data), vaultHeader)
+}
+
+// ReadVaultPassword reads the password from file, or from the output of file if it is executable
+func ReadVaultPassword(file string) (string, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	if stat.Mode()&0111 != 0 {
+		data, err = exec.Command(file).Output()
+	} else {
+		data, err = ioutil.ReadFile(file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading vault password from %s: %v", file, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// VaultDecrypt decrypts an ansible-vault 1.1 or 1.2 AES256 payload
+func VaultDecrypt(data string, password string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	header := strings.Split(strings.TrimSpace(lines[0]), ";")
+	if len(header) < 3 || header[0] != vaultHeader {
+		return "", errors.New("invalid vault header")
+	}
+	if strings.TrimSpace(header[2]) != "AES256" {
+		return "", fmt.Errorf("unsupported vault cipher %s", header[2])
+	}
+
+	var body string
+	for _, line := range lines[1:] {
+		body += strings.TrimSpace(line)
+	}
+	decoded, err := hex.DecodeString(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault encoding: %v", err)
+	}
+	parts := strings.Split(string(decoded), "\n")
+	if len(parts) != 3 {
+		return "", errors.New("invalid vault payload")
+	}
+	salt, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return "", err
+	}
+	expectedMac, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, 10000, 80, sha256.New)
+	mac := hmac.New(sha256.New, key[32:64])
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), expectedMac) {
+		return "", errors.New("vault password does not match")
+	}
+
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return "", err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, key[64:80]).XORKeyStream(plaintext, ciphertext)
+
+	// strip the PKCS7 padding
+	if len(plaintext) > 0 {
+		padding := int(plaintext[len(plaintext)-1])
+		if padding > 0 && padding <= aes.BlockSize && padding <= len(plaintext) &&
+			bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+			plaintext = plaintext[:len(plaintext)-padding]
+		}
+	}
+	return string(plaintext), nil
+}
+
+// DecryptVars replaces all inline !vault values with their decrypted value
+func (inv Inventory) DecryptVars(vars map[string]interface{}) {
+	for k, v := range vars {
+		vars[k] = inv.decrypt(k, v)
+	}
+}
+
+func (inv Inventory) decrypt(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if !IsVaultEncrypted(v) {
+			return v
+		}
+		if inv.VaultPassword == "" {
+			log.Warnf("Cannot decrypt %s, no vault password file specified", key)
+			return v
+		}
+		out, err := VaultDecrypt(v, inv.VaultPassword)
+		if err != nil {
+			log.Errorf("Error decrypting %s: %v", key, err)
+			return v
+		}
+		inv.Decrypted[key] = true
+		return out
+	case []interface{}:
+		for i, val := range v {
+			v[i] = inv.decrypt(key, val)
+		}
+	case map[string]interface{}:
+		for subkey, val := range v {
+			v[subkey] = inv.decrypt(subkey, val)
+		}
+	}
+	return value
+}
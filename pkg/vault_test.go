@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+// the payloads were encrypted following the ansible-vault 1.1 format with an implementation independent of
+// VaultDecrypt: PBKDF2-SHA256 (10000 iterations, 80 byte key) from python hashlib, AES-256-CTR from openssl
+// and HMAC-SHA256 over the ciphertext
+const vaultPassword = "correct horse"
+
+const vaultSecret = `$ANSIBLE_VAULT;1.1;AES256
+35333136376566353966306639643266323039656231353866306261386466623933306438303462
+3433656137613331396436653934656161386635373364320a343635393465646338613130393739
+39393435663065303838613531386538376362633637653731383139393163336164623862366137
+6632323030633336660a333431313964336139366537373065383539353061633965373135636562
+6435`
+
+// a plaintext of exactly one block is padded with a full block
+const vaultBlock = `$ANSIBLE_VAULT;1.1;AES256
+63363963393631333130306335393830383432613366626466353539643734306664373937383838
+3464626337343030393762623734343436393937623164630a316536323364393536383064666338
+63393266363630303130323165353661353131383565326133316431306134633562363565396530
+6432323763353366350a303838353166313734326666623162316435396334303666623639393332
+65366266383235386435333463636264616631663365386536316164303862623835`
+
+func TestVaultDecrypt(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"1.1", vaultSecret, "db: s3(cr)et\n"},
+		{"1.2 with vault id", strings.Replace(vaultSecret, "1.1;AES256", "1.2;AES256;prod", 1), "db: s3(cr)et\n"},
+		{"inline !vault indentation", strings.Replace(vaultSecret, "\n", "\n          ", -1), "db: s3(cr)et\n"},
+		{"full padding block", vaultBlock, "0123456789abcdef"},
+	}
+	for _, test := range tests {
+		out, err := VaultDecrypt(test.data, vaultPassword)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		} else if out != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, out)
+		}
+	}
+}
+
+func TestVaultDecryptErrors(t *testing.T) {
+	tampered := []byte(vaultSecret)
+	// flip a digit of the ciphertext so that the hmac no longer matches
+	if tampered[len(tampered)-1] == '5' {
+		tampered[len(tampered)-1] = '6'
+	} else {
+		tampered[len(tampered)-1] = '5'
+	}
+	tests := []struct {
+		name     string
+		data     string
+		password string
+	}{
+		{"wrong password", vaultSecret, "wrong"},
+		{"tampered ciphertext", string(tampered), vaultPassword},
+		{"unsupported cipher", strings.Replace(vaultSecret, "AES256", "AES", 1), vaultPassword},
+		{"invalid header", strings.Replace(vaultSecret, "$ANSIBLE_VAULT", "$VAULT", 1), vaultPassword},
+		{"invalid encoding", vaultSecret + "zz", vaultPassword},
+	}
+	for _, test := range tests {
+		if out, err := VaultDecrypt(test.data, test.password); err == nil {
+			t.Errorf("%s: expected an error, got %q", test.name, out)
+		}
+	}
+}
+
+func TestIsVaultEncrypted(t *testing.T) {
+	if !IsVaultEncrypted("\n  " + vaultSecret) {
+		t.Error("expected an indented vault payload to be detected")
+	}
+	if IsVaultEncrypted("password: $ANSIBLE_VAULT") {
+		t.Error("expected a value mentioning the header not to be detected")
+	}
+}
@@ -0,0 +1,272 @@
+package pkg
+
+import (
+	"github.com/docker/cli/cli/compose/types"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const DefaultVolumeSize = "1Gi"
+
+const (
+	// PersistentVolume is backed by a PersistentVolumeClaim, or a volumeClaimTemplate for statefulsets
+	PersistentVolume = "persistent"
+	// HostVolume mounts Source from the node
+	HostVolume = "host"
+	// TmpfsVolume is a memory backed emptyDir
+	TmpfsVolume = "tmpfs"
+	// EmptyVolume is a disk backed emptyDir
+	EmptyVolume = "empty"
+)
+
+// Volume is a volume mounted into a container at Path
+type Volume struct {
+	Name         string `json:"name,omitempty"`
+	Path         string `json:"path"`
+	Type         string `json:"type,omitempty"`
+	Source       string `json:"source,omitempty"`
+	Size         string `json:"size,omitempty"`
+	StorageClass string `json:"storage_class,omitempty"`
+	AccessMode   string `json:"access_mode,omitempty"`
+	ReadOnly     bool   `json:"read_only,omitempty"`
+}
+
+// NewVolumeFromMount parses a docker style source:target[:ro] mount, where source is either
+// a volume name, an absolute host path or tmpfs
+func NewVolumeFromMount(source string, target string) Volume {
+	parts := strings.Split(target, ":")
+	vol := Volume{Path: parts[0]}
+	if len(parts) > 1 && parts[1] == "ro" {
+		vol.ReadOnly = true
+	}
+	switch {
+	case strings.HasPrefix(source, "/"):
+		vol.Type = HostVolume
+		vol.Source = source
+	case strings.HasPrefix(source, "tmpfs"):
+		vol.Type = TmpfsVolume
+	default:
+		vol.Type = PersistentVolume
+		vol.Name = source
+	}
+	return vol
+}
+
+func NewVolumeFromCompose(volume types.ServiceVolumeConfig) Volume {
+	vol := Volume{
+		Path:     volume.Target,
+		ReadOnly: volume.ReadOnly,
+	}
+	switch volume.Type {
+	case "bind":
+		vol.Type = HostVolume
+		vol.Source = volume.Source
+	case "tmpfs":
+		vol.Type = TmpfsVolume
+		if volume.Tmpfs != nil && volume.Tmpfs.Size > 0 {
+			vol.Size = strconv.FormatInt(volume.Tmpfs.Size, 10)
+		}
+	default:
+		// anonymous volumes have no source and are named after their path
+		vol.Type = PersistentVolume
+		vol.Name = volume.Source
+	}
+	return vol
+}
+
+func (vol Volume) ToType() string {
+	if vol.Type != "" {
+		return strings.ToLower(vol.Type)
+	}
+	if vol.Source != "" {
+		return HostVolume
+	}
+	return PersistentVolume
+}
+
+var invalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// ToDNSLabel lower cases name and replaces the characters that are invalid in volume and claim names,
+// such as the underscores common in compose volume names
+func ToDNSLabel(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return strings.Trim(name, "-")
+}
+
+// ToName returns the volume name, derived from the mount path when no name is specified, the vol- prefix
+// keeps it apart from the config map volumes that are named after their directory
+func (vol Volume) ToName() string {
+	if vol.Name != "" {
+		return ToDNSLabel(vol.Name)
+	}
+	return "vol-" + ToDNSLabel(strings.Trim(vol.Path, "/"))
+}
+
+func (vol Volume) ToAccessMode() v1.PersistentVolumeAccessMode {
+	switch strings.ToLower(vol.AccessMode) {
+	case "readwritemany", "rwx":
+		return v1.ReadWriteMany
+	case "readonlymany", "rox":
+		return v1.ReadOnlyMany
+	case "readwriteoncepod", "rwop":
+		return v1.ReadWriteOncePod
+	}
+	return v1.ReadWriteOnce
+}
+
+func (vol Volume) ToPersistentVolumeClaimSpec() v1.PersistentVolumeClaimSpec {
+	size, err := resource.ParseQuantity(vol.Size)
+	if err != nil {
+		if vol.Size != "" {
+			log.Warnf("Invalid size %s for volume %s, using %s", vol.Size, vol.ToName(), DefaultVolumeSize)
+		}
+		size = resource.MustParse(DefaultVolumeSize)
+	}
+	spec := v1.PersistentVolumeClaimSpec{
+		AccessModes: []v1.PersistentVolumeAccessMode{vol.ToAccessMode()},
+		Resources: v1.VolumeResourceRequirements{
+			Requests: v1.ResourceList{
+				v1.ResourceStorage: size,
+			},
+		},
+	}
+	if vol.StorageClass != "" {
+		spec.StorageClassName = &vol.StorageClass
+	}
+	return spec
+}
+
+// ToVolumeSource returns the pod volume source, volumeClaimTemplates are used instead for persistent volumes of statefulsets
+func (c Container) ToVolumeSource(vol Volume) v1.VolumeSource {
+	switch vol.ToType() {
+	case HostVolume:
+		return v1.VolumeSource{
+			HostPath: &v1.HostPathVolumeSource{Path: vol.Source},
+		}
+	case TmpfsVolume, EmptyVolume:
+		emptyDir := &v1.EmptyDirVolumeSource{}
+		if vol.ToType() == TmpfsVolume {
+			emptyDir.Medium = v1.StorageMediumMemory
+		}
+		if size, err := resource.ParseQuantity(vol.Size); err == nil {
+			emptyDir.SizeLimit = &size
+		}
+		return v1.VolumeSource{EmptyDir: emptyDir}
+	}
+	return v1.VolumeSource{
+		PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+			ClaimName: c.ClaimName(vol),
+			ReadOnly:  vol.ReadOnly,
+		},
+	}
+}
+
+// ClaimName prefixes the volume name with the service, as volume names are only unique within a container
+func (c Container) ClaimName(vol Volume) string {
+	return c.Service + "-" + vol.ToName()
+}
+
+func (c Container) ToVolumes() []v1.Volume {
+	var volumes []v1.Volume
+	for _, vol := range c.Volumes {
+		if c.Kind == StatefulSetKind && vol.ToType() == PersistentVolume {
+			continue
+		}
+		volumes = append(volumes, v1.Volume{
+			Name:         vol.ToName(),
+			VolumeSource: c.ToVolumeSource(vol),
+		})
+	}
+	return volumes
+}
+
+func (c Container) ToVolumeMounts() []v1.VolumeMount {
+	var mounts []v1.VolumeMount
+	for _, vol := range c.Volumes {
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      vol.ToName(),
+			MountPath: vol.Path,
+			ReadOnly:  vol.ReadOnly,
+		})
+	}
+	return mounts
+}
+
+// ToPersistentVolumeClaims returns the claims for persistent volumes of all kinds except statefulsets
+func (c Container) ToPersistentVolumeClaims() []interface{} {
+	var claims []interface{}
+	if c.Kind == StatefulSetKind {
+		return claims
+	}
+	for _, vol := range c.Volumes {
+		if vol.ToType() != PersistentVolume {
+			continue
+		}
+		if c.Replicas > 1 && vol.ToAccessMode() == v1.ReadWriteOnce {
+			log.Warnf("[%s] Volume %s is ReadWriteOnce but there are %d replicas", c.Service, vol.ToName(), c.Replicas)
+		}
+		claims = append(claims, v1.PersistentVolumeClaim{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "PersistentVolumeClaim",
+			},
+			ObjectMeta: c.ToObjectMeta(c.ClaimName(vol)),
+			Spec: vol.ToPersistentVolumeClaimSpec(),
+		})
+	}
+	return claims
+}
+
+func (c Container) ToVolumeClaimTemplates() []v1.PersistentVolumeClaim {
+	var claims []v1.PersistentVolumeClaim
+	for _, vol := range c.Volumes {
+		if vol.ToType() != PersistentVolume {
+			continue
+		}
+		claims = append(claims, v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: vol.ToName(),
+			},
+			Spec: vol.ToPersistentVolumeClaimSpec(),
+		})
+	}
+	return claims
+}
+
+// applyVolumeDefaults converts Mounts into volumes and fills in unspecified persistent volume settings
+func (c *Container) applyVolumeDefaults(defaults Volume) {
+	for _, source := range sortedKeys(c.Mounts) {
+		c.Volumes = append(c.Volumes, NewVolumeFromMount(source, c.Mounts[source]))
+	}
+	for i := range c.Volumes {
+		vol := &c.Volumes[i]
+		if vol.ToType() != PersistentVolume {
+			continue
+		}
+		if vol.Size == "" {
+			vol.Size = defaults.Size
+		}
+		if vol.StorageClass == "" {
+			vol.StorageClass = defaults.StorageClass
+		}
+		if vol.AccessMode == "" {
+			vol.AccessMode = defaults.AccessMode
+		}
+	}
+	for _, vol := range c.Volumes {
+		if vol.Path == "" {
+			log.Errorf("[%s] Missing path for volume %s", c.Service, vol.ToName())
+		}
+		if vol.ToType() == HostVolume && vol.Source == "" {
+			log.Errorf("[%s] Missing source for host volume %s", c.Service, vol.ToName())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+)
+
+const (
+	DeploymentKind  = "deployment"
+	StatefulSetKind = "statefulset"
+	DaemonSetKind   = "daemonset"
+	JobKind         = "job"
+	CronJobKind     = "cronjob"
+)
+
+// ToWorkload returns the workload for the container kind along with any objects it requires
+func (c Container) ToWorkload() []interface{} {
+	switch c.Kind {
+	case StatefulSetKind:
+		return []interface{}{c.ToHeadlessService(), c.ToStatefulSet()}
+	case DaemonSetKind:
+		return []interface{}{c.ToDaemonSet()}
+	case JobKind:
+		return []interface{}{c.ToJob()}
+	case CronJobKind:
+		return []interface{}{c.ToCronJob()}
+	case DeploymentKind:
+	default:
+		log.Warnf("[%s] Unknown kind %s, using %s", c.Service, c.Kind, DeploymentKind)
+	}
+	return []interface{}{c.ToK8Deployment()}
+}
+
+// HeadlessServiceName is the governing service of a statefulset that provides stable pod DNS names
+func (c Container) HeadlessServiceName() string {
+	return c.Service + "-headless"
+}
+
+func (c Container) ToHeadlessService() v1.Service {
+	service := c.ToService()
+	service.Name = c.HeadlessServiceName()
+	service.Spec.Type = v1.ServiceTypeClusterIP
+	service.Spec.ClusterIP = v1.ClusterIPNone
+	service.Spec.PublishNotReadyAddresses = true
+	return service
+}
+
+func (c Container) ToStatefulSet() appsv1.StatefulSet {
+	statefulset := appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: c.KubeVersion().StatefulSetAPIVersion(),
+			Kind:       "StatefulSet",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:             c.ToReplicas(),
+			Selector:             c.ToSelector(),
+			ServiceName:          c.HeadlessServiceName(),
+			Template:             c.ToPodTemplate(),
+			VolumeClaimTemplates: c.ToPodVolumeClaimTemplates(),
+		},
+	}
+
+	if strings.ToLower(c.PodManagementPolicy) == "parallel" {
+		statefulset.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+	} else if c.PodManagementPolicy != "" {
+		statefulset.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+	}
+
+	if strings.ToLower(c.UpdateStrategy) == "ondelete" {
+		statefulset.Spec.UpdateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType
+	} else if c.UpdateStrategy != "" {
+		statefulset.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	}
+	return statefulset
+}
+
+func (c Container) ToDaemonSet() appsv1.DaemonSet {
+	template := c.ToPodTemplate()
+	if c.RestartPolicy != "" && c.ToRestartPolicy() != v1.RestartPolicyAlways {
+		log.Warnf("[%s] Daemonsets only support restart_policy Always", c.Service)
+	}
+	return appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: c.KubeVersion().AppsAPIVersion(),
+			Kind:       "DaemonSet",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: appsv1.DaemonSetSpec{
+			Selector: c.ToSelector(),
+			Template: template,
+		},
+	}
+}
+
+func (c Container) ToRestartPolicy() v1.RestartPolicy {
+	switch strings.ToLower(c.RestartPolicy) {
+	case "never":
+		return v1.RestartPolicyNever
+	case "onfailure", "on-failure":
+		return v1.RestartPolicyOnFailure
+	case "always":
+		return v1.RestartPolicyAlways
+	}
+	if c.Kind == JobKind || c.Kind == CronJobKind {
+		// jobs cannot use the pod default of Always
+		return v1.RestartPolicyOnFailure
+	}
+	return v1.RestartPolicyAlways
+}
+
+func (c Container) ToJobSpec() batchv1.JobSpec {
+	template := c.ToPodTemplate()
+	template.Spec.RestartPolicy = c.ToRestartPolicy()
+	if template.Spec.RestartPolicy == v1.RestartPolicyAlways {
+		log.Warnf("[%s] Jobs do not support restart_policy Always, using OnFailure", c.Service)
+		template.Spec.RestartPolicy = v1.RestartPolicyOnFailure
+	}
+	return batchv1.JobSpec{
+		BackoffLimit: c.BackoffLimit,
+		Completions:  c.Completions,
+		Template:     template,
+	}
+}
+
+func (c Container) ToJob() batchv1.Job {
+	return batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: c.KubeVersion().JobAPIVersion(),
+			Kind:       "Job",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: c.ToJobSpec(),
+	}
+}
+
+func (c Container) ToConcurrencyPolicy() batchv1.ConcurrencyPolicy {
+	switch strings.ToLower(c.ConcurrencyPolicy) {
+	case "forbid":
+		return batchv1.ForbidConcurrent
+	case "replace":
+		return batchv1.ReplaceConcurrent
+	case "allow":
+		return batchv1.AllowConcurrent
+	case "":
+		return ""
+	}
+	log.Warnf("[%s] Unknown concurrency_policy %s, using Allow", c.Service, c.ConcurrencyPolicy)
+	return batchv1.AllowConcurrent
+}
+
+// ToCronJob returns a batch/v1 CronJob, or the legacy batch/v1beta1 equivalent for clusters older than 1.21
+func (c Container) ToCronJob() interface{} {
+	if c.Schedule == "" {
+		log.Errorf("[%s] Missing schedule for cronjob", c.Service)
+	}
+	version := c.KubeVersion()
+	if !version.AtLeast(8) {
+		log.Fatalf("[%s] CronJobs require kube_version 1.8 or later, got %s", c.Service, version)
+	}
+	cronjob := batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1",
+			Kind:       "CronJob",
+		},
+		ObjectMeta: c.ToObjectMeta(c.Service),
+		Spec: batchv1.CronJobSpec{
+			Schedule:          c.Schedule,
+			ConcurrencyPolicy: c.ToConcurrencyPolicy(),
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: c.ToJobSpec(),
+			},
+		},
+	}
+	if version.AtLeast(21) {
+		return cronjob
+	}
+	legacy := ToLegacyCronJob(cronjob)
+	legacy.APIVersion = version.CronJobAPIVersion()
+	return legacy
+}
+
+// ToLegacyCronJob converts a CronJob to the batch/v1beta1 schema
+func ToLegacyCronJob(cronjob batchv1.CronJob) batchv1beta1.CronJob {
+	spec := cronjob.Spec
+	return batchv1beta1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "batch/v1beta1",
+			Kind:       "CronJob",
+		},
+		ObjectMeta: cronjob.ObjectMeta,
+		Spec: batchv1beta1.CronJobSpec{
+			Schedule:                   spec.Schedule,
+			TimeZone:                   spec.TimeZone,
+			StartingDeadlineSeconds:    spec.StartingDeadlineSeconds,
+			ConcurrencyPolicy:          batchv1beta1.ConcurrencyPolicy(spec.ConcurrencyPolicy),
+			Suspend:                    spec.Suspend,
+			SuccessfulJobsHistoryLimit: spec.SuccessfulJobsHistoryLimit,
+			FailedJobsHistoryLimit:     spec.FailedJobsHistoryLimit,
+			JobTemplate: batchv1beta1.JobTemplateSpec{
+				ObjectMeta: spec.JobTemplate.ObjectMeta,
+				Spec:       spec.JobTemplate.Spec,
+			},
+		},
+	}
+}